@@ -1,536 +1,5093 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
+	"github.com/atotto/clipboard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/fatih/color"
+	"github.com/gofrs/flock"
+	"github.com/juliar13/awsid/pkg/awsid"
+	"github.com/ktr0731/go-fuzzyfinder"
+	"github.com/mattn/go-isatty"
 	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+	_ "modernc.org/sqlite"
 )
 
-type AccountInfo struct {
-	ID            string `json:"id"`
-	Arn           string `json:"arn"`
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	Status        string `json:"status"`
-	JoinedMethod  string `json:"joined_method"`
-	JoinedTimestamp string `json:"joined_timestamp"`
-	// Backward compatibility fields
-	AliasName string `json:"alias_name"`
-	AccountID string `json:"account_id"`
+type AccountInfoList struct {
+	Meta     *OutputMeta         `json:"meta,omitempty" yaml:"meta,omitempty"`
+	Accounts []awsid.AccountInfo `json:"account_info" yaml:"account_info"`
 }
 
-type AccountInfoList struct {
-	Accounts []AccountInfo `json:"account_info"`
+// OutputMeta describes how a result was produced, output with --with-meta
+type OutputMeta struct {
+	Count       int    `json:"count" yaml:"count"`               // Accounts in the result
+	Total       int    `json:"total" yaml:"total"`               // Accounts in the cache before filters and search
+	GeneratedAt string `json:"generated_at" yaml:"generated_at"` // RFC 3339 time the result was produced
+	SourceFile  string `json:"source_file" yaml:"source_file"`   // Cache file read, comma-separated when --file was repeated
+}
+
+// formatTimestamps rewrites joined timestamps for display, converting them to
+// the local time zone when local is set and formatting them with layout
+// (timestampLayout when empty). Values that cannot be parsed are left as is.
+func formatTimestamps(accounts []awsid.AccountInfo, local bool, layout string) {
+	if layout == "" {
+		layout = timestampLayout
+	}
+	for i := range accounts {
+		joined, err := awsid.ParseTimestamp(accounts[i].JoinedTimestamp)
+		if err != nil {
+			continue
+		}
+		if local {
+			joined = joined.Local()
+		}
+		accounts[i].JoinedTimestamp = joined.Format(layout)
+	}
+}
+
+// relativeTimestamps rewrites joined timestamps for display as the time
+// elapsed before now. Values that cannot be parsed are left as is.
+func relativeTimestamps(accounts []awsid.AccountInfo, now time.Time) {
+	for i := range accounts {
+		joined, err := awsid.ParseTimestamp(accounts[i].JoinedTimestamp)
+		if err != nil {
+			continue
+		}
+		accounts[i].JoinedTimestamp = formatRelativeTime(now.Sub(joined))
+	}
+}
+
+// formatRelativeTime formats an elapsed duration as English text such as
+// "3 months ago", using the largest unit that fits. Months are 30 days and
+// years 365 days.
+func formatRelativeTime(elapsed time.Duration) string {
+	day := 24 * time.Hour
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		if n := int(elapsed / unit.size); n >= 1 {
+			if n == 1 {
+				return fmt.Sprintf("1 %s ago", unit.name)
+			}
+			return fmt.Sprintf("%d %ss ago", n, unit.name)
+		}
+	}
+	return "just now"
+}
+
+// setAgeDays fills in AgeDays for JSON and YAML output, leaving it unset for
+// accounts whose joined timestamp cannot be parsed
+func setAgeDays(accounts []awsid.AccountInfo) {
+	for i := range accounts {
+		if days, ok := awsid.DaysSinceJoined(accounts[i]); ok {
+			accounts[i].AgeDays = &days
+		}
+	}
+}
+
+// selectedAccount is an account restricted to a subset of fields, marshaled in field order
+type selectedAccount struct {
+	fields  []string
+	account awsid.AccountInfo
+}
+
+func (s selectedAccount) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range s.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(s.account.FieldValue(field))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (s selectedAccount) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range s.fields {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s.account.FieldValue(field)},
+		)
+	}
+	return node, nil
+}
+
+type selectedAccountList struct {
+	Meta     *OutputMeta       `json:"meta,omitempty" yaml:"meta,omitempty"`
+	Accounts []selectedAccount `json:"account_info" yaml:"account_info"`
+}
+
+// Build details, set at build time with -ldflags, e.g.
+// -ldflags "-X main.Version=0.5.0 -X main.Commit=$(git rev-parse HEAD) -X main.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)".
+// Values left empty are filled in by resolveBuildInfo.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// defaultVersion is the version reported when it is neither injected nor
+// recorded in the module build info, as with a local go build
+const defaultVersion = "0.5.0"
+
+// releaseVersionPattern matches the module version of a release tag, as opposed
+// to the pseudo-versions the Go toolchain stamps on untagged builds
+var releaseVersionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
+// resolveBuildInfo fills in the build details not injected with -ldflags from
+// the build info embedded by the Go toolchain: the module version of a
+// "go install ...@version" build, and the VCS revision and time of a build
+// from a git checkout
+func resolveBuildInfo() {
+	info, ok := debug.ReadBuildInfo()
+	if Version == "" {
+		Version = defaultVersion
+		if ok && releaseVersionPattern.MatchString(info.Main.Version) {
+			Version = strings.TrimPrefix(info.Main.Version, "v")
+		}
+	}
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && Commit == "":
+			Commit = setting.Value
+		case setting.Key == "vcs.time" && BuildDate == "":
+			BuildDate = setting.Value
+		}
+	}
+}
+
+// versionInfo is the output of --version in the json and yaml formats
+type versionInfo struct {
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty" yaml:"build_date,omitempty"`
 }
 
-const Version = "0.5.0"
+// writeVersion prints the version in the given format: json, yaml, or text
+// when format is empty
+func writeVersion(w io.Writer, format string) error {
+	info := versionInfo{Version: Version, Commit: Commit, BuildDate: BuildDate}
+	switch format {
+	case "":
+		line := "awsid version " + info.Version
+		if info.Commit != "" {
+			line += " (commit " + info.Commit + ")"
+		}
+		if info.BuildDate != "" {
+			line += " built " + info.BuildDate
+		}
+		_, err := fmt.Fprintln(w, line)
+		return err
+	case "json":
+		jsonData, err := json.MarshalIndent(info, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(jsonData))
+		return err
+	case "yaml":
+		yamlData, err := yaml.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("failed to create YAML: %w", err)
+		}
+		_, err = w.Write(yamlData)
+		return err
+	default:
+		return fmt.Errorf("version supports --format json or yaml, not %q", format)
+	}
+}
 
 func main() {
+	resolveBuildInfo()
+
 	var jsonOutput bool
+	var jsonArray bool
 	var tableOutput bool
 	var csvOutput bool
+	var yamlOutput bool
+	var idsOnly bool
+	var namesOnly bool
 	var nameSearch string
 	var formatOption string
 	var sortField string
+	var naturalSort bool
 	var sortDesc string
-	var rootCmd = &cobra.Command{
-		Use:     "awsid [alias_name]",
-		Short:   "Get AWS account ID from alias name",
-		Long:    "A CLI tool to get AWS account ID from alias name. Supports both positional arguments and --name option.",
-		Version: Version,
-		Args:  cobra.MinimumNArgs(0),
-		Run: func(cmd *cobra.Command, args []string) {
+	var outputPath string
+	var fieldsOption string
+	var idSearch string
+	var ignoreCase bool
+	var regexSearch string
+	var noUpdate bool
+	var maxAge time.Duration
+	var profile string
+	var region string
+	var roleARN string
+	var externalID string
+	var endpointURL string
+	var maxRetries int
+	var statusOption string
+	var emailDomainOption string
+	var templateOption string
+	var colorMode string
+	var allowEmpty bool
+	var filePaths []string
+	var configPath string
+	var withTags bool
+	var tagOptions []string
+	var withOU bool
+	var ouFilter string
+	var ouRecursive bool
+	var searchAll string
+	var limit int
+	var noHeader bool
+	var delimiterOption string
+	var compact bool
+	var readStdin bool
+	var mapMulti bool
+	var roleName string
+	var consoleOutput bool
+	var openConsole bool
+	var copyID bool
+	var verbose bool
+	var debug bool
+	var quietFlag bool
+	var timeout time.Duration
+	var syncS3 string
+	var countOnly bool
+	var keepFirst bool
+	var prefixSearch string
+	var suffixSearch string
+	var globSearch string
+	var sinceOption string
+	var untilOption string
+	var withAge bool
+	var localTime bool
+	var timeFormat string
+	var relativeTime bool
+	var varName string
+	var groupBy string
+	var maxColWidth int
+	var noTruncate bool
+	var pagerMode string
+	var interactive bool
+	var noHistory bool
+	var withSource bool
+	var withMeta bool
+	var notifyNew bool
+	var webhookURL string
+	// singleFilePath returns the --file path for commands that write a single
+	// cache, or "" for the default path, exiting when --file was repeated
+	singleFilePath := func(command string) string {
+		if len(filePaths) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: %s accepts only one --file\n", command)
+			os.Exit(1)
+		}
+		if len(filePaths) == 1 {
+			return filePaths[0]
+		}
+		return ""
+	}
+	// validatedAWSOptions builds the AWS options from the global flags,
+	// exiting when they or --sync-s3 are invalid
+	validatedAWSOptions := func() AWSOptions {
+		awsOptions := AWSOptions{Profile: profile, Region: region, RoleARN: roleARN, ExternalID: externalID, MaxRetries: maxRetries, Timeout: timeout, EndpointURL: endpointURL}
+		if err := validateAWSOptions(awsOptions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := validateSyncS3(syncS3); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return awsOptions
+	}
+	// readAccounts refreshes the account info cache from AWS when it is stale
+	// and reads it. Several --file caches are merged and deduplicated by ID.
+	// It returns errInterrupted when the update is interrupted by a signal.
+	readAccounts := func(awsOptions AWSOptions) ([]awsid.AccountInfo, error) {
+		accountInfoPaths, err := resolveAccountInfoPaths(filePaths)
+		if err != nil {
+			return nil, err
+		}
+
+		var accounts []awsid.AccountInfo
+		for _, accountInfoPath := range accountInfoPaths {
+			// Try to update account info from AWS Organizations unless running offline
+			// or the cached file is newer than --max-age
+			logger.Info("using account info cache", "path", accountInfoPath)
+			if len(accountInfoPaths) > 1 {
+				// Merged caches usually belong to different organizations, which one set of credentials cannot refresh
+				logger.Info("several account info caches given, skipping AWS update")
+			} else if isS3URI(accountInfoPath) {
+				logger.Info("account info on S3 is read-only, skipping AWS update")
+			} else if isSQLiteFile(accountInfoPath) {
+				logger.Info("account info in a SQLite database is read-only, skipping AWS update")
+			} else if !noUpdate && !isCacheFresh(accountInfoPath, maxAge) {
+				result, err := updateAccountInfoInterruptibly(accountInfoPath, awsOptions, FetchOptions{WithTags: withTags, WithOU: withOU})
+				if errors.Is(err, errInterrupted) {
+					return nil, err
+				}
+				if errors.Is(err, errCacheLocked) {
+					warnf("Warning: %v. Skipping the update and using the existing cache\n", err)
+				} else if err != nil {
+					warnf("Warning: Failed to update account info from AWS: %v\n", err)
+					if hint := awsErrorHint(err); hint != "" {
+						warnf("%s\n", hint)
+					}
+				} else {
+					reportUpdateChanges(result.Diff, notifyNew)
+					if syncS3 != "" {
+						if err := uploadAccountInfoToS3(accountInfoPath, syncS3, awsOptions); err != nil {
+							warnf("Warning: Failed to sync account info to S3: %v\n", err)
+						}
+					}
+				}
+			} else if !noUpdate {
+				logger.Info("cache is newer than --max-age, skipping AWS update", "max_age", maxAge)
+			}
+
+			// Read account_info file
+			if !isS3URI(accountInfoPath) {
+				warnLoosePermissions(accountInfoPath)
+			}
+			fileAccounts, err := readAccountInfo(accountInfoPath, awsOptions)
+			if err != nil {
+				if noUpdate && os.IsNotExist(err) {
+					return nil, fmt.Errorf("account info file %s does not exist. Run without --no-update to fetch it from AWS Organizations", accountInfoPath)
+				}
+				return nil, fmt.Errorf("failed to read account info: %w", err)
+			}
+			logger.Info("read account info cache", "path", accountInfoPath, "accounts", len(fileAccounts))
+			if withSource {
+				for i := range fileAccounts {
+					fileAccounts[i].Source = accountInfoPath
+				}
+			}
+			accounts = append(accounts, fileAccounts...)
+		}
+
+		deduped := awsid.DedupeAccounts(accounts, keepFirst)
+		if removed := len(accounts) - len(deduped); removed > 0 {
+			logger.Info("removed duplicate account rows", "rows", removed, "keep_first", keepFirst)
+		}
+		return deduped, nil
+	}
+	// loadAccounts runs readAccounts for commands that read the cache once,
+	// exiting on errors
+	loadAccounts := func(awsOptions AWSOptions) []awsid.AccountInfo {
+		accounts, err := readAccounts(awsOptions)
+		if errors.Is(err, errInterrupted) {
+			fmt.Fprintln(os.Stderr, "Interrupted")
+			os.Exit(exitInterrupted)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return accounts
+	}
+	runSearch := func(cmd *cobra.Command, args []string) {
+		// Apply defaults from the config file to flags not given on the command line
+		if err := applyConfigFile(cmd, configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := setupDiagnostics(verbose, debug, quietFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// --count prints only the number of matches, so output format flags are ignored
+		resolvedFormat := "default"
+		var err error
+		if !countOnly {
 			// Validate and resolve format flags
-			resolvedFormat, err := resolveFormatFlags(formatOption, jsonOutput, tableOutput, csvOutput)
+			resolvedFormat, err = resolveFormatFlags(formatOption, map[string]bool{
+				"json":       jsonOutput,
+				"json-array": jsonArray,
+				"table":      tableOutput,
+				"csv":        csvOutput,
+				"yaml":       yamlOutput,
+				"ids":        idsOnly,
+				"names":      namesOnly,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// --template renders each account itself and cannot be combined with a format
+			if templateOption != "" {
+				if resolvedFormat != "default" {
+					fmt.Fprintf(os.Stderr, "Error: cannot specify both --template and an output format. Use only one output option\n")
+					os.Exit(1)
+				}
+				if _, err := parseTemplate(templateOption); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				resolvedFormat = "template"
+			}
+
+			// --console prints switch role URLs instead of account details
+			if consoleOutput {
+				if resolvedFormat != "default" {
+					fmt.Fprintf(os.Stderr, "Error: cannot specify both --console and an output format. Use only one output option\n")
+					os.Exit(1)
+				}
+				if roleName == "" {
+					fmt.Fprintf(os.Stderr, "Error: --console requires --role\n")
+					os.Exit(1)
+				}
+				resolvedFormat = "console"
+			}
+			if openConsole && !consoleOutput {
+				fmt.Fprintf(os.Stderr, "Error: --open requires --console\n")
+				os.Exit(1)
+			}
+
+			if resolvedFormat == "aws-config" && roleName == "" {
+				fmt.Fprintf(os.Stderr, "Error: --format aws-config requires --role-name\n")
+				os.Exit(1)
+			}
+
+			// A SQLite database is binary and only useful as a whole file
+			if resolvedFormat == "sqlite" {
+				if outputPath == "" && (isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())) {
+					fmt.Fprintf(os.Stderr, "Error: --format sqlite writes a binary database. Use --output FILE or redirect stdout\n")
+					os.Exit(1)
+				}
+				if groupBy != "" {
+					fmt.Fprintf(os.Stderr, "Error: cannot specify both --format sqlite and --group-by\n")
+					os.Exit(1)
+				}
+			}
+
+			// An Excel workbook is only written to a file, never to stdout
+			if resolvedFormat == "xlsx" {
+				if outputPath == "" {
+					fmt.Fprintf(os.Stderr, "Error: --format xlsx requires --output FILE\n")
+					os.Exit(1)
+				}
+				if groupBy != "" {
+					fmt.Fprintf(os.Stderr, "Error: cannot specify both --format xlsx and --group-by\n")
+					os.Exit(1)
+				}
+			}
+
+			// Metadata wraps the account_info list, which only the json and yaml formats have
+			if withMeta {
+				if resolvedFormat != "json" && resolvedFormat != "yaml" {
+					fmt.Fprintf(os.Stderr, "Error: --with-meta requires --format json or yaml\n")
+					os.Exit(1)
+				}
+				if groupBy != "" {
+					fmt.Fprintf(os.Stderr, "Error: cannot specify both --with-meta and --group-by\n")
+					os.Exit(1)
+				}
+			}
+		}
+
+		// Validate color mode
+		if err := validateColorMode(colorMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := validatePagerMode(pagerMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Validate and resolve CSV delimiter
+		var delimiter rune
+		if cmd.Flags().Changed("delimiter") {
+			delimiter, err = resolveDelimiter(delimiterOption)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-			
-			// Validate and resolve sort flags
-			resolvedSort, err := resolveSortFlags(sortField, sortDesc)
+		}
+
+		// Validate and resolve sort flags
+		resolvedSort, err := resolveSortFlags(sortField, sortDesc, naturalSort)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Validate and resolve output columns
+		resolvedFields, err := resolveFieldsFlag(fieldsOption)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Only one search mode can be used at a time
+		searchModes := 0
+		for _, used := range []bool{nameSearch != "" || len(args) > 0, idSearch != "", regexSearch != "", searchAll != "", readStdin, prefixSearch != "" || suffixSearch != "", globSearch != "", interactive} {
+			if used {
+				searchModes++
+			}
+		}
+		if searchModes > 1 {
+			fmt.Fprintf(os.Stderr, "Error: multiple search options specified. Use only one of --name (or positional argument), --id, --regex, --search-all, --glob, --stdin, --prefix/--suffix, --interactive\n")
+			os.Exit(1)
+		}
+		if countOnly && readStdin {
+			fmt.Fprintf(os.Stderr, "Error: cannot specify both --count and --stdin\n")
+			os.Exit(1)
+		}
+		if interactive && !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+			fmt.Fprintf(os.Stderr, "Error: --interactive requires a terminal. Search with an alias name or --name, --regex, --glob instead\n")
+			os.Exit(1)
+		}
+
+		// Validate --glob pattern
+		if globSearch != "" {
+			if err := validateGlobPattern(globSearch); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Compile --regex pattern
+		var searchRegex *regexp.Regexp
+		if regexSearch != "" {
+			searchRegex, err = compileSearchRegex(regexSearch, ignoreCase)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-			// Get home directory
-			homeDir, err := os.UserHomeDir()
+		}
+
+		awsOptions := validatedAWSOptions()
+		if cmd.Flags().Changed("limit") && limit <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --limit must be 1 or greater\n")
+			os.Exit(1)
+		}
+		if maxColWidth < 2 {
+			fmt.Fprintf(os.Stderr, "Error: --max-col-width must be 2 or greater\n")
+			os.Exit(1)
+		}
+		if noTruncate && cmd.Flags().Changed("max-col-width") {
+			fmt.Fprintf(os.Stderr, "Error: cannot specify both --max-col-width and --no-truncate\n")
+			os.Exit(1)
+		}
+
+		// Validate and resolve status filter
+		statuses, err := resolveStatusFlag(statusOption)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Validate and resolve tag filter
+		tagFilter, err := resolveTagFlags(tagOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(tagFilter) > 0 && !withTags {
+			fmt.Fprintf(os.Stderr, "Error: --tag requires --with-tags to fetch account tags\n")
+			os.Exit(1)
+		}
+		if ouFilter != "" && !withOU {
+			fmt.Fprintf(os.Stderr, "Error: --ou requires --with-ou to fetch organizational units\n")
+			os.Exit(1)
+		}
+		if err := validateGroupBy(groupBy); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if groupBy == "ou" && !withOU {
+			fmt.Fprintf(os.Stderr, "Error: --group-by ou requires --with-ou to fetch organizational units\n")
+			os.Exit(1)
+		}
+
+		if relativeTime && (localTime || timeFormat != "") {
+			fmt.Fprintf(os.Stderr, "Error: cannot specify --relative-time with --local-time or --time-format\n")
+			os.Exit(1)
+		}
+
+		// Validate and resolve joined timestamp range
+		since, until, err := resolveJoinedRange(sinceOption, untilOption)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		outputManager := NewOutputManager(os.Stdout)
+		outputManager.Fields = resolvedFields
+		outputManager.Template = templateOption
+		outputManager.NoHeader = noHeader
+		outputManager.Delimiter = delimiter
+		outputManager.Compact = compact
+		outputManager.MapMulti = mapMulti
+		outputManager.RoleName = roleName
+		outputManager.SourceProfile = profile
+		outputManager.GroupBy = groupBy
+		if !noTruncate {
+			outputManager.MaxColWidth = maxColWidth
+		}
+		if withTags {
+			outputManager.ExtraColumns = append(outputManager.ExtraColumns, "tags")
+		}
+		if withOU {
+			outputManager.ExtraColumns = append(outputManager.ExtraColumns, "ou_name", "ou_path")
+		}
+		if withAge {
+			outputManager.ExtraColumns = append(outputManager.ExtraColumns, "age_days")
+		}
+		if withSource {
+			outputManager.ExtraColumns = append(outputManager.ExtraColumns, "source")
+		}
+
+		accounts := loadAccounts(awsOptions)
+		total := len(accounts)
+
+		// Determine search term: --name option takes priority over positional argument
+		var searchTerm string
+		if nameSearch != "" {
+			searchTerm = nameSearch
+		} else if len(args) > 0 {
+			searchTerm = args[0]
+		}
+
+		// Filters apply before searching so they affect both search results and full listing
+		criteria := awsid.Criteria{
+			Filter: awsid.Filter{
+				Statuses:     statuses,
+				EmailDomains: splitCommaSeparated(emailDomainOption),
+				Tags:         tagFilter,
+				OU:           ouFilter,
+				OURecursive:  ouRecursive,
+				JoinedSince:  since,
+				JoinedUntil:  until,
+			},
+			Query: awsid.Query{
+				ID:         idSearch,
+				All:        searchAll,
+				Prefix:     prefixSearch,
+				Suffix:     suffixSearch,
+				Glob:       globSearch,
+				Regex:      searchRegex,
+				IgnoreCase: ignoreCase,
+			},
+		}
+		// Terms are trimmed, so "prod " still finds the exact match for "prod"
+		searchTerms := splitCommaSeparated(searchTerm)
+		if len(searchTerms) > 1 {
+			criteria.Names = searchTerms
+		} else if len(searchTerms) == 1 {
+			criteria.Name = searchTerms[0]
+		}
+
+		// A short alias from ~/.awsid-aliases expands to the full account name
+		var aliases []accountAlias
+		if criteria.Name != "" && !interactive {
+			if aliases, err = readAliases(aliasesPath()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		var results []awsid.AccountInfo
+		isExactMatch := false
+		var notFoundMessage string
+		if interactive {
+			// The picked account is treated as an exact match, so the default format prints its ID
+			selected, err := selectAccountInteractively(awsid.FilterAccounts(accounts, criteria.Filter))
+			if errors.Is(err, fuzzyfinder.ErrAbort) {
+				os.Exit(exitInterrupted)
+			}
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			results = []awsid.AccountInfo{selected}
+			isExactMatch = true
+		} else {
+			results, isExactMatch, criteria = selectAccountsWithAlias(accounts, criteria, aliases)
+
+			// SearchAccounts uses the first search option given, in the same order as here
+			switch {
+			case idSearch != "":
+				// Reverse lookup: exact ID match first, then ID prefix match
+				notFoundMessage = fmt.Sprintf("No account found with account ID: %s", idSearch)
+				outputManager.ReverseLookup = true
+			case searchAll != "":
+				// Substring match across all fields, always listed
+				notFoundMessage = fmt.Sprintf("No account found containing: %s", searchAll)
+				outputManager.Highlight = literalRegexp([]string{searchAll}, ignoreCase)
+				outputManager.HighlightAll = true
+			case prefixSearch != "" || suffixSearch != "":
+				// Anchored matches are always listed; both flags must match when combined
+				notFoundMessage = fmt.Sprintf("No account found with alias name matching: %s*%s", prefixSearch, suffixSearch)
+				outputManager.Highlight = affixRegexp(prefixSearch, suffixSearch, ignoreCase)
+			case globSearch != "":
+				// Glob matches are always listed, like regex matches
+				notFoundMessage = fmt.Sprintf("No account found matching glob: %s", globSearch)
+			case searchRegex != nil:
+				// Regex matches have no notion of an exact match
+				notFoundMessage = fmt.Sprintf("No account found matching pattern: %s", regexSearch)
+				outputManager.Highlight = searchRegex
+			case len(criteria.Names) > 0:
+				// Multiple comma-separated terms: OR search, always listed
+				notFoundMessage = fmt.Sprintf("No account found with alias names: %s", strings.Join(criteria.Names, ", "))
+				outputManager.Highlight = literalRegexp(criteria.Names, ignoreCase)
+			case criteria.Name != "":
+				// An exact match takes priority over partial matches
+				notFoundMessage = fmt.Sprintf("No account found with alias name: %s", criteria.Name)
+				outputManager.Highlight = literalRegexp([]string{criteria.Name}, ignoreCase)
+			}
+		}
+
+		logger.Info("searched accounts", "results", len(results))
+
+		// --count reports the number of matches, including 0, instead of the accounts
+		if countOnly {
+			fmt.Println(len(results))
+			return
+		}
+
+		// No matches found: fail unless --allow-empty, which outputs the empty result instead
+		if len(results) == 0 && notFoundMessage != "" {
+			if !allowEmpty {
+				fmt.Fprintln(os.Stderr, notFoundMessage)
 				os.Exit(1)
 			}
+			warnf("%s\n", notFoundMessage)
+		}
 
-			// Path to account_info file
-			accountInfoPath := filepath.Join(homeDir, ".aws", "account_info")
+		awsid.SortAccounts(results, resolvedSort.Keys)
+		if limit > 0 && len(results) > limit {
+			results = results[:limit]
+		}
+		// A partial match could otherwise open a browser tab for every account
+		if openConsole && len(results) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: --open requires exactly one matching account, found %d. Narrow the search or add --limit 1\n", len(results))
+			os.Exit(1)
+		}
+		// Computed before the timestamps are converted for display, which would make them unparsable
+		if withAge || slices.Contains(resolvedFields, "age_days") {
+			setAgeDays(results)
+		}
+		if withMeta {
+			// The paths resolved when loading the accounts, so this cannot fail
+			accountInfoPaths, _ := resolveAccountInfoPaths(filePaths)
+			outputManager.Meta = &OutputMeta{
+				Count:       len(results),
+				Total:       total,
+				GeneratedAt: time.Now().Format(time.RFC3339),
+				SourceFile:  strings.Join(accountInfoPaths, ","),
+			}
+		}
+		// Timestamps are converted only for display, after sorting and age calculation
+		if localTime || timeFormat != "" {
+			formatTimestamps(results, localTime, timeFormat)
+		} else if relativeTime {
+			relativeTimestamps(results, time.Now())
+		}
 
-			// Try to update account info from AWS Organizations
-			err = updateAccountInfoFromAWS(accountInfoPath)
+		// Write to the --output file if given, otherwise to stdout
+		if outputPath != "" {
+			file, err := os.Create(outputPath)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to update account info from AWS: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+				os.Exit(1)
 			}
+			defer file.Close()
+			outputManager.Writer = file
+		}
+		outputManager.Color = shouldColorize(colorMode, outputManager.Writer)
 
-			// Read account_info file
-			accounts, err := readAccountInfo(accountInfoPath)
+		// --stdin resolves each input line by itself and writes name,id pairs
+		if readStdin {
+			missing, err := resolveNames(os.Stdin, outputManager.Writer, awsid.FilterAccounts(accounts, criteria.Filter), ignoreCase)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading account info: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error resolving names from stdin: %v\n", err)
+				os.Exit(1)
+			}
+			for _, name := range missing {
+				if allowEmpty {
+					warnf("No account found with alias name: %s\n", name)
+				} else {
+					fmt.Fprintf(os.Stderr, "No account found with alias name: %s\n", name)
+				}
+			}
+			if len(missing) > 0 && !allowEmpty {
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Buffer terminal output so it can be sent to a pager once its length is known
+		var paged *bytes.Buffer
+		if usePager(pagerMode, outputManager.Writer) {
+			paged = &bytes.Buffer{}
+			outputManager.Writer = paged
+		}
+
+		if err := outputManager.Output(results, resolvedFormat, isExactMatch); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		if paged != nil {
+			if err := writeWithPager(pagerMode, paged.Bytes()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Copying is a convenience, so a missing clipboard (e.g. headless) is only a warning
+		if copyID && len(results) > 0 {
+			if err := clipboard.WriteAll(results[0].ID); err != nil {
+				warnf("Warning: Failed to copy account ID to clipboard: %v\n", err)
+			}
+		}
+
+		if openConsole {
+			if err := openBrowser(consoleURL(results[0], roleName)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
 				os.Exit(1)
 			}
+		}
 
-			// Determine search term: --name option takes priority over positional argument
-			var searchTerm string
-			if nameSearch != "" {
-				searchTerm = nameSearch
-			} else if len(args) > 0 {
-				searchTerm = args[0]
+		// A search that resolved to a single account is recorded for awsid history
+		if !noHistory {
+			if err := recordSearch(historyPath(), searchModes, results, time.Now()); err != nil {
+				warnf("Warning: Failed to record search history: %v\n", err)
 			}
+		}
+	}
 
-			// If search term is provided, search for matching accounts
-			if searchTerm != "" {
-				matchingAccounts := []AccountInfo{}
+	// Output format, columns, sorting and destination
+	addOutputFlags := func(flags *pflag.FlagSet) {
+		flags.BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+		flags.BoolVar(&jsonArray, "json-array", false, "Output a top-level JSON array without the account_info wrapper (same as --format json-array)")
+		flags.StringVar(&roleName, "role-name", "", "IAM role name assumed in each account by the aws-config format and --console, e.g. OrganizationAccountAccessRole")
+		flags.StringVar(&roleName, "role", "", "Alias for --role-name")
+		flags.BoolVar(&consoleOutput, "console", false, "Print the switch role console URL of each account (requires --role)")
+		flags.BoolVar(&copyID, "copy", false, "Copy the account ID of the first result to the clipboard")
+		flags.BoolVar(&openConsole, "open", false, "Open the --console URLs in the default browser")
+		flags.BoolVar(&mapMulti, "map-multi", false, "In map format, output an array of IDs for every name so duplicate names are not lost")
+		flags.BoolVar(&withMeta, "with-meta", false, "Wrap json and yaml output with a meta object holding the result count, the total before filtering, the generation time and the cache file")
+		flags.BoolVar(&compact, "compact", false, "Write json, json-array and map output on a single line without indentation")
+		flags.BoolVar(&tableOutput, "table", false, "Output in table format")
+		flags.BoolVar(&csvOutput, "csv", false, "Output in CSV format")
+		flags.BoolVar(&yamlOutput, "yaml", false, "Output in YAML format")
+		flags.BoolVar(&idsOnly, "ids-only", false, "Output only account IDs, one per line (same as --format ids)")
+		flags.BoolVar(&namesOnly, "names-only", false, "Output only account names, one per line (same as --format names)")
+		flags.StringVar(&formatOption, "format", "", "Output format ("+strings.Join(awsid.Formats(), ", ")+")")
+		flags.StringVar(&delimiterOption, "delimiter", "", "Single-character field separator for CSV output (default \",\"), e.g. ';' or '|'")
+		flags.BoolVar(&noHeader, "no-header", false, "Omit the header row in CSV and TSV output")
+		flags.StringVar(&templateOption, "template", "", "Render each account with a Go template, e.g. '{{.Name}}: {{.ID}}' (fields: ID, Arn, Email, Name, Status, JoinedMethod, JoinedTimestamp)")
+		flags.StringVar(&pagerMode, "pager", "auto", "Send output to $PAGER (default less -FRX) on a terminal (auto, always, never). auto pages only output taller than the terminal")
+		flags.StringVar(&colorMode, "color", "auto", "Colorize table output by status and highlight search matches (auto, always, never). auto enables colors only on a terminal")
+		flags.StringVar(&fieldsOption, "fields", "", "Comma-separated columns to output (id, arn, email, name, status, joined_method, joined_timestamp, tags, ou_id, ou_name, ou_path, ou_id_path, age_days, source)")
+		flags.BoolVar(&localTime, "local-time", false, "Display joined timestamps in the local time zone")
+		flags.StringVar(&timeFormat, "time-format", "", "Go time layout used to display joined timestamps, e.g. '2006-01-02 15:04'")
+		flags.BoolVar(&relativeTime, "relative-time", false, "Display joined timestamps relative to now, e.g. \"2 years ago\"")
+		flags.BoolVar(&withSource, "with-source", false, "Add a source column with the --file each account was read from")
+		flags.BoolVar(&withAge, "with-age", false, "Add an age_days column with the number of days since each account joined")
+		flags.StringVar(&sortField, "sort", "", "Sort by comma-separated fields, each optionally suffixed with :desc (id, name, email, status, joined_timestamp, joined_method, age_days)")
+		flags.StringVar(&sortDesc, "sort-desc", "", "Sort by comma-separated fields in descending order, each optionally suffixed with :asc (id, name, email, status, joined_timestamp, joined_method, age_days)")
+		flags.BoolVar(&naturalSort, "natural-sort", false, "Sort names in natural order, comparing numbers by value (name-2 before name-10)")
+		flags.IntVar(&maxColWidth, "max-col-width", defaultMaxColWidth, "Maximum display width of table cells; longer values are cut off with …")
+		flags.BoolVar(&noTruncate, "no-truncate", false, "Show full table cell values instead of cutting them at --max-col-width")
+		flags.StringVar(&groupBy, "group-by", "", "Output accounts in sections per status, joined_method or ou (requires --with-ou). JSON and YAML output an object keyed by group")
+		flags.IntVar(&limit, "limit", 0, "Output at most this many accounts after sorting")
+		flags.BoolVar(&countOnly, "count", false, "Print only the number of matching accounts after filtering and searching; other output flags are ignored")
+		flags.BoolVar(&allowEmpty, "allow-empty", false, "Exit with status 0 and output an empty result when no account matches")
+		flags.StringVarP(&outputPath, "output", "o", "", "Write output to the given file instead of stdout (overwrites existing file)")
+	}
+	// Filters applied before searching or listing
+	addFilterFlags := func(flags *pflag.FlagSet) {
+		flags.StringVar(&statusOption, "status", "", "Filter by account status, comma-separated (ACTIVE, SUSPENDED, PENDING_CLOSURE)")
+		flags.StringVar(&emailDomainOption, "email-domain", "", "Filter by email domain, comma-separated (e.g. example.com)")
+		flags.StringArrayVar(&tagOptions, "tag", nil, "Filter by tag key=value (requires --with-tags). Repeat for AND across keys; values for the same key are ORed")
+		flags.StringVar(&ouFilter, "ou", "", "Filter by organizational unit name, ID or path (requires --with-ou)")
+		flags.BoolVar(&ouRecursive, "ou-recursive", false, "Include accounts in child OUs of --ou")
+		flags.StringVar(&sinceOption, "since", "", "Keep accounts that joined at or after this date (2006-01-02, local time) or RFC 3339 timestamp")
+		flags.StringVar(&untilOption, "until", "", "Keep accounts that joined at or before this date (through the end of the day, local time) or RFC 3339 timestamp")
+		flags.BoolVar(&keepFirst, "keep-first", false, "When an account ID appears on several rows of the cache, use the first row instead of the last")
+	}
+	// Search modes used by the root and get commands
+	addSearchFlags := func(flags *pflag.FlagSet) {
+		flags.StringVar(&nameSearch, "name", "", "Search by account name, comma-separated for OR search (takes priority over positional argument)")
+		flags.StringVar(&idSearch, "id", "", "Search by account ID (exact or prefix match) and print the account name")
+		flags.StringVar(&regexSearch, "regex", "", "Search by account name using a regular expression")
+		flags.StringVar(&globSearch, "glob", "", "Search by account name using a wildcard pattern with * and ?, e.g. 'prod-*-web'")
+		flags.StringVar(&prefixSearch, "prefix", "", "Search for account names starting with this string (combined with --suffix, both must match)")
+		flags.StringVar(&suffixSearch, "suffix", "", "Search for account names ending with this string (combined with --prefix, both must match)")
+		flags.StringVar(&searchAll, "search-all", "", "Search for a substring in ID, ARN, email, name and status")
+		flags.BoolVar(&interactive, "interactive", false, "Pick an account from the cache with an incremental fuzzy finder and output it")
+		flags.BoolVar(&readStdin, "stdin", false, "Resolve alias names read from stdin, one per line, by exact match and print name,id lines")
+		flags.BoolVarP(&ignoreCase, "ignore-case", "i", false, "Ignore case when searching by name")
+		flags.BoolVar(&noHistory, "no-history", false, "Do not record the resolved account in the search history (~/.awsid-history)")
+	}
+	// Cache file location, config file and AWS access used to refresh the cache
+	addCacheFlags := func(flags *pflag.FlagSet) {
+		flags.StringVar(&configPath, "config", "", "Path of the config file with default flag values (default ~/.awsid.yaml)")
+		flags.StringArrayVar(&filePaths, "file", nil, "Path of the account info cache file to read and update (default ~/.aws/account_info). Repeat to search several caches merged, without updating them")
+		flags.StringVar(&profile, "profile", "", "AWS shared config profile used to access AWS Organizations")
+		flags.StringVar(&region, "region", defaultRegion, "AWS region used for the Organizations API (e.g. us-gov-west-1, cn-north-1)")
+		flags.StringVar(&roleARN, "role-arn", "", "IAM role ARN to assume before listing accounts")
+		flags.StringVar(&externalID, "external-id", "", "External ID used when assuming --role-arn")
+		flags.StringVar(&endpointURL, "endpoint-url", "", "Send AWS API calls to this endpoint instead of the AWS one, e.g. http://localhost:4566 for LocalStack. AWS_ENDPOINT_URL is honored as well")
+		flags.IntVar(&maxRetries, "max-retries", defaultMaxRetries, "Maximum number of retries for throttled or failed AWS API calls")
+		flags.StringVar(&syncS3, "sync-s3", "", "Upload the account info cache to this s3://bucket/key URI after updating it from AWS")
+		flags.DurationVar(&timeout, "timeout", defaultTimeout, "Deadline for updating from AWS (e.g. 10s). On timeout the cached file is used. 0 disables")
+		flags.BoolVar(&withTags, "with-tags", false, "Fetch Organizations tags for each account (one extra API call per account) and output a tags column")
+		flags.BoolVar(&notifyNew, "notify-new", false, "List the accounts added since the last update on stderr after updating from AWS")
+		flags.BoolVar(&withOU, "with-ou", false, "Fetch the parent organizational unit of each account (extra API calls) and output OU columns")
+	}
+	// Diagnostic logging to stderr
+	addLogFlags := func(flags *pflag.FlagSet) {
+		flags.BoolVarP(&verbose, "verbose", "v", false, "Log cache and AWS activity to stderr")
+		flags.BoolVar(&debug, "debug", false, "Log debug details to stderr, including AWS request IDs")
+		flags.BoolVarP(&quietFlag, "quiet", "q", false, "Suppress warnings and informational messages on stderr; fatal errors are still printed")
+	}
+	// When to refresh the cache before reading it; update always refreshes
+	addRefreshFlags := func(flags *pflag.FlagSet) {
+		flags.BoolVar(&noUpdate, "no-update", false, "Skip updating account info from AWS and use the cached file only")
+		flags.BoolVar(&noUpdate, "offline", false, "Alias for --no-update")
+		flags.DurationVar(&maxAge, "max-age", 0, "Skip updating from AWS if the cached file is newer than this duration (e.g. 1h, 30m). 0 always updates")
+	}
+
+	var rootCmd = &cobra.Command{
+		Use:     "awsid [alias_name]",
+		Short:   "Get AWS account ID from alias name",
+		Long:    "A CLI tool to get AWS account ID from alias name. Supports both positional arguments and --name option.\nThe get, list and update subcommands are also available; a bare \"awsid <alias_name>\" works like \"awsid get\".",
+		Version: Version,
+		Args:    cobra.MinimumNArgs(0),
+		Run:     runSearch,
+	}
+	addSearchFlags(rootCmd.Flags())
+	addFilterFlags(rootCmd.Flags())
+	addOutputFlags(rootCmd.Flags())
+	addCacheFlags(rootCmd.Flags())
+	addLogFlags(rootCmd.Flags())
+	addRefreshFlags(rootCmd.Flags())
 
-				for _, account := range accounts {
-					if strings.Contains(account.AliasName, searchTerm) {
-						matchingAccounts = append(matchingAccounts, account)
+	// --version follows --format so scripts can read it as JSON or YAML
+	cobra.AddTemplateFunc("awsidVersion", func() string {
+		var buf bytes.Buffer
+		if err := writeVersion(&buf, formatOption); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return buf.String()
+	})
+	rootCmd.SetVersionTemplate("{{awsidVersion}}")
+
+	var getCmd = &cobra.Command{
+		Use:   "get [alias_name]",
+		Short: "Search accounts by alias name, ID, regex or any field",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 && nameSearch == "" && idSearch == "" && regexSearch == "" && globSearch == "" && prefixSearch == "" && suffixSearch == "" && searchAll == "" && !readStdin && !interactive {
+				fmt.Fprintf(os.Stderr, "Error: get requires an alias name or one of --name, --id, --regex, --glob, --prefix, --suffix, --search-all, --stdin, --interactive\n")
+				os.Exit(1)
+			}
+			runSearch(cmd, args)
+		},
+	}
+	addSearchFlags(getCmd.Flags())
+	addFilterFlags(getCmd.Flags())
+	addOutputFlags(getCmd.Flags())
+	addCacheFlags(getCmd.Flags())
+	addLogFlags(getCmd.Flags())
+	addRefreshFlags(getCmd.Flags())
+
+	var listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all accounts",
+		Args:  cobra.NoArgs,
+		Run:   runSearch,
+	}
+	addFilterFlags(listCmd.Flags())
+	addOutputFlags(listCmd.Flags())
+	addCacheFlags(listCmd.Flags())
+	addLogFlags(listCmd.Flags())
+	addRefreshFlags(listCmd.Flags())
+
+	var updateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "Update the account info cache from AWS Organizations without output",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := applyConfigFile(cmd, configPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := setupDiagnostics(verbose, debug, quietFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			awsOptions := validatedAWSOptions()
+			if err := validateWebhookURL(webhookURL); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Path to account_info file: --file or ~/.aws/account_info
+			accountInfoPath := singleFilePath("update")
+			if accountInfoPath == "" {
+				var err error
+				accountInfoPath, err = defaultAccountInfoPath()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if isS3URI(accountInfoPath) {
+				fmt.Fprintf(os.Stderr, "Error: update requires a local --file, not an S3 URI\n")
+				os.Exit(1)
+			}
+			if isSQLiteFile(accountInfoPath) {
+				fmt.Fprintf(os.Stderr, "Error: update requires a CSV --file, not a SQLite database\n")
+				os.Exit(1)
+			}
+
+			result, err := updateAccountInfoInterruptibly(accountInfoPath, awsOptions, FetchOptions{WithTags: withTags, WithOU: withOU})
+			if errors.Is(err, errInterrupted) {
+				fmt.Fprintln(os.Stderr, "Interrupted")
+				os.Exit(exitInterrupted)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to update account info from AWS: %v\n", err)
+				if hint := awsErrorHint(err); hint != "" {
+					fmt.Fprintln(os.Stderr, hint)
+				}
+				os.Exit(1)
+			}
+			warnf("Updated %d accounts in %s\n", result.Count, accountInfoPath)
+			reportUpdateChanges(result.Diff, notifyNew)
+			if syncS3 != "" {
+				if err := uploadAccountInfoToS3(accountInfoPath, syncS3, awsOptions); err != nil {
+					warnf("Warning: Failed to sync account info to S3: %v\n", err)
+				}
+			}
+
+			// Only added or removed accounts are notified; the first update has nothing to compare with
+			if webhookURL != "" && result.Diff != nil && (len(result.Diff.Added) > 0 || len(result.Diff.Removed) > 0) {
+				if err := postWebhook(webhookURL, newWebhookPayload(accountInfoPath, *result.Diff)); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: cache updated but the webhook notification failed: %v\n", err)
+					os.Exit(exitWebhookFailed)
+				}
+				// The URL is not logged since Slack webhook URLs are secrets
+				logger.Info("notified account changes to the webhook", "added", len(result.Diff.Added), "removed", len(result.Diff.Removed))
+			}
+		},
+	}
+	updateCmd.Flags().StringVar(&webhookURL, "webhook", "", "POST a JSON notification, readable as a Slack message, to this URL when accounts were added or removed")
+	addCacheFlags(updateCmd.Flags())
+	addLogFlags(updateCmd.Flags())
+	// Output flags are accepted so shared aliases and scripts keep working, but update prints nothing
+	ignoredFlags := pflag.NewFlagSet("output", pflag.ContinueOnError)
+	addOutputFlags(ignoredFlags)
+	ignoredFlags.VisitAll(func(flag *pflag.Flag) {
+		flag.Hidden = true
+	})
+	updateCmd.Flags().AddFlagSet(ignoredFlags)
+
+	var migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Convert an old two-column account info file to the current format",
+		Long: "Convert an old alias_name,account_id account info file to the current format.\n" +
+			"Missing details are filled in from AWS Organizations when reachable; otherwise only the columns are normalized.\n" +
+			"The original file is kept as <file>.bak.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := applyConfigFile(cmd, configPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := setupDiagnostics(verbose, debug, quietFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			awsOptions := validatedAWSOptions()
+
+			// Path to account_info file: --file or ~/.aws/account_info
+			accountInfoPath := singleFilePath("migrate")
+			if accountInfoPath == "" {
+				var err error
+				accountInfoPath, err = defaultAccountInfoPath()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if isS3URI(accountInfoPath) {
+				fmt.Fprintf(os.Stderr, "Error: migrate requires a local --file, not an S3 URI\n")
+				os.Exit(1)
+			}
+			if isSQLiteFile(accountInfoPath) {
+				fmt.Fprintf(os.Stderr, "Error: migrate requires a CSV --file, not a SQLite database\n")
+				os.Exit(1)
+			}
+
+			accounts, err := readAccountInfo(accountInfoPath, awsOptions)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading account info: %v\n", err)
+				os.Exit(1)
+			}
+			if !needsMigration(accounts) {
+				warnf("%s is already in the current format\n", accountInfoPath)
+				return
+			}
+
+			remote, err := fetchAccountsInterruptibly(awsOptions, FetchOptions{WithTags: withTags, WithOU: withOU})
+			if errors.Is(err, errInterrupted) {
+				fmt.Fprintln(os.Stderr, "Interrupted")
+				os.Exit(exitInterrupted)
+			}
+			if err != nil {
+				warnf("Warning: Failed to fetch account details from AWS, normalizing columns only: %v\n", err)
+				if hint := awsErrorHint(err); hint != "" {
+					warnf("%s\n", hint)
+				}
+			}
+			merged := mergeAccountDetails(accounts, remote)
+
+			backupPath, err := backupFile(accountInfoPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error backing up account info: %v\n", err)
+				os.Exit(1)
+			}
+			if err := saveAccountInfoToCSV(accountInfoPath, accounts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing account info: %v\n", err)
+				os.Exit(1)
+			}
+			warnf("Migrated %d accounts in %s (%d completed from AWS, backup: %s)\n", len(accounts), accountInfoPath, merged, backupPath)
+			if syncS3 != "" {
+				if err := uploadAccountInfoToS3(accountInfoPath, syncS3, awsOptions); err != nil {
+					warnf("Warning: Failed to sync account info to S3: %v\n", err)
+				}
+			}
+		},
+	}
+	addCacheFlags(migrateCmd.Flags())
+	addLogFlags(migrateCmd.Flags())
+
+	var envCmd = &cobra.Command{
+		Use:   "env <alias_name>",
+		Short: "Print an export statement setting the ID of an account, for use with eval",
+		Long: "Print \"export AWS_ACCOUNT_ID=<id>\" for the account whose alias name matches exactly.\n" +
+			"Use it as eval \"$(awsid env <alias_name>)\" to load the ID into the current shell.\n" +
+			"Fails when no account or more than one account has the name.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := applyConfigFile(cmd, configPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := setupDiagnostics(verbose, debug, quietFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := validateEnvVarName(varName); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			awsOptions := validatedAWSOptions()
+
+			accounts := loadAccounts(awsOptions)
+			exactMatch, exact := awsid.SearchAccounts(accounts, awsid.Query{Name: args[0], IgnoreCase: ignoreCase})
+			if !exact {
+				fmt.Fprintf(os.Stderr, "No account found with alias name: %s\n", args[0])
+				os.Exit(1)
+			}
+			if len(exactMatch) > 1 {
+				ids := make([]string, len(exactMatch))
+				for i, account := range exactMatch {
+					ids[i] = account.ID
+				}
+				fmt.Fprintf(os.Stderr, "Error: %d accounts have the alias name %s: %s\n", len(exactMatch), args[0], strings.Join(ids, ", "))
+				os.Exit(1)
+			}
+
+			fmt.Printf("export %s=%s\n", varName, shellQuote(exactMatch[0].ID))
+		},
+	}
+	envCmd.Flags().StringVar(&varName, "var-name", "AWS_ACCOUNT_ID", "Name of the exported environment variable")
+	envCmd.Flags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "Ignore case when matching the alias name")
+	addCacheFlags(envCmd.Flags())
+	addLogFlags(envCmd.Flags())
+	addRefreshFlags(envCmd.Flags())
+
+	var diffFormat string
+	var diffColorMode string
+	var diffCmd = &cobra.Command{
+		Use:   "diff <old_file> <new_file>",
+		Short: "Show accounts added, removed or changed between two account info files",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if diffFormat != "text" && diffFormat != "json" {
+				fmt.Fprintf(os.Stderr, "Error: invalid diff format \"%s\". Supported formats: text, json\n", diffFormat)
+				os.Exit(1)
+			}
+			if err := validateColorMode(diffColorMode); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			oldAccounts, err := readAccountInfo(args[0], AWSOptions{})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", args[0], err)
+				os.Exit(1)
+			}
+			newAccounts, err := readAccountInfo(args[1], AWSOptions{})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", args[1], err)
+				os.Exit(1)
+			}
+
+			diff := diffAccountInfo(oldAccounts, newAccounts)
+			if diffFormat == "json" {
+				err = writeDiffJSON(os.Stdout, diff)
+			} else {
+				err = writeDiffText(os.Stdout, diff, shouldColorize(diffColorMode, os.Stdout))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format (text, json)")
+	diffCmd.Flags().StringVar(&diffColorMode, "color", "auto", "Colorize text output (auto, always, never). auto enables colors only on a terminal")
+
+	var statsFile string
+	var statsFormat string
+	var statsByEmailDomain bool
+	var statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Show account counts by status and joined method from the cache",
+		Long: "Show account counts by status and joined method, optionally by email domain.\n" +
+			"Only the cached account info file is read; AWS is never called.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if statsFormat != "table" && statsFormat != "json" {
+				fmt.Fprintf(os.Stderr, "Error: invalid stats format \"%s\". Supported formats: table, json\n", statsFormat)
+				os.Exit(1)
+			}
+
+			// Path to account_info file: --file or ~/.aws/account_info
+			accountInfoPath := statsFile
+			if accountInfoPath == "" {
+				var err error
+				accountInfoPath, err = defaultAccountInfoPath()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			accounts, err := readAccountInfo(accountInfoPath, AWSOptions{})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading account info: %v\n", err)
+				os.Exit(1)
+			}
+
+			stats := computeAccountStats(accounts, statsByEmailDomain)
+			if statsFormat == "json" {
+				err = writeStatsJSON(os.Stdout, stats)
+			} else {
+				err = writeStatsTable(os.Stdout, stats)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	statsCmd.Flags().StringVar(&statsFile, "file", "", "Path of the account info cache file to read (default ~/.aws/account_info)")
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "Output format (table, json)")
+	statsCmd.Flags().BoolVar(&statsByEmailDomain, "by-email-domain", false, "Also count accounts by email domain")
+
+	var versionFormat string
+	var versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Show the version, commit and build date",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if versionFormat != "" {
+				if err := writeVersion(os.Stdout, versionFormat); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			unknown := func(value string) string {
+				if value == "" {
+					return "unknown"
+				}
+				return value
+			}
+			fmt.Printf("Version:    %s\n", Version)
+			fmt.Printf("Commit:     %s\n", unknown(Commit))
+			fmt.Printf("Build date: %s\n", unknown(BuildDate))
+		},
+	}
+	versionCmd.Flags().StringVar(&versionFormat, "format", "", "Output format (json, yaml)")
+
+	var validateFile string
+	var validateFix bool
+	var aliasCmd = &cobra.Command{
+		Use:   "alias",
+		Short: "Manage short aliases for account names in ~/.awsid-aliases",
+		Long: "Manage short aliases for account names. \"awsid <alias>\" searches for the exact account name\n" +
+			"the alias stands for, falling back to a normal search when no account has that name.",
+	}
+	aliasAddCmd := &cobra.Command{
+		Use:   "add <alias> <account_name>",
+		Short: "Add an alias, replacing any existing one with the same name",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateAlias(args[0], args[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			path := aliasesPath()
+			aliases, err := readAliases(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			replaced := false
+			for i := range aliases {
+				if aliases[i].Name == args[0] {
+					aliases[i].Target = args[1]
+					replaced = true
+				}
+			}
+			if !replaced {
+				aliases = append(aliases, accountAlias{Name: args[0], Target: args[1]})
+			}
+			if err := writeAliases(path, aliases); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	aliasRemoveCmd := &cobra.Command{
+		Use:     "remove <alias>",
+		Aliases: []string{"rm"},
+		Short:   "Remove an alias",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := aliasesPath()
+			aliases, err := readAliases(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			kept := aliases[:0]
+			for _, alias := range aliases {
+				if alias.Name != args[0] {
+					kept = append(kept, alias)
+				}
+			}
+			if len(kept) == len(aliases) {
+				fmt.Fprintf(os.Stderr, "Error: alias \"%s\" is not defined\n", args[0])
+				os.Exit(1)
+			}
+			if err := writeAliases(path, kept); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	aliasListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the aliases as alias=account_name lines",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			aliases, err := readAliases(aliasesPath())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, alias := range aliases {
+				fmt.Printf("%s=%s\n", alias.Name, alias.Target)
+			}
+		},
+	}
+	aliasCmd.AddCommand(aliasAddCmd, aliasRemoveCmd, aliasListCmd)
+
+	var historyLimit int
+	var historyClear bool
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Show the accounts recently resolved by searches, newest first",
+		Long: "Show the accounts recently resolved by searches, newest first.\n" +
+			"A search is recorded in ~/.awsid-history when it resolves to a single account, unless --no-history is given.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path := historyPath()
+			if historyClear {
+				if err := clearHistory(path); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+			if historyLimit < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --limit must be 0 or greater\n")
+				os.Exit(1)
+			}
+
+			entries, err := readHistory(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeHistory(os.Stdout, entries, historyLimit); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Show at most this many entries. 0 shows all")
+	historyCmd.Flags().BoolVar(&historyClear, "clear", false, "Delete the search history")
+
+	var validateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check the account info cache for broken rows, duplicate IDs and invalid values",
+		Long: "Check each row of the account info cache for its column count, missing fields, a 12-digit account ID,\n" +
+			"a known status, a valid email address, surrounding whitespace and duplicate account IDs.\n" +
+			"Each problem is printed on its own line with its line number. Exits with status 1 when any problem is found.\n" +
+			"With --fix, whitespace is trimmed and duplicate rows are removed before checking again; the original file is kept as <file>.bak.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Path to account_info file: --file or ~/.aws/account_info
+			accountInfoPath := validateFile
+			if accountInfoPath == "" {
+				var err error
+				accountInfoPath, err = defaultAccountInfoPath()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if isS3URI(accountInfoPath) {
+				fmt.Fprintf(os.Stderr, "Error: validate requires a local --file, not an S3 URI\n")
+				os.Exit(1)
+			}
+			if isSQLiteFile(accountInfoPath) {
+				fmt.Fprintf(os.Stderr, "Error: validate requires a CSV --file, not a SQLite database\n")
+				os.Exit(1)
+			}
+
+			check := func() accountInfoReport {
+				file, err := os.Open(accountInfoPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading account info: %v\n", err)
+					os.Exit(1)
+				}
+				defer file.Close()
+
+				report, err := validateAccountInfoFile(file)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading account info: %v\n", err)
+					os.Exit(1)
+				}
+				return report
+			}
+
+			report := check()
+			if validateFix {
+				if report.legacyRows > 0 {
+					fmt.Fprintf(os.Stderr, "Error: --fix cannot rewrite old two-column rows. Run awsid migrate first\n")
+					os.Exit(1)
+				}
+				fixed := awsid.DedupeAccounts(report.accounts, keepFirst)
+				removed := len(report.accounts) - len(fixed)
+				if removed == 0 && report.untrimmedRows == 0 {
+					warnf("Nothing to fix in %s\n", accountInfoPath)
+				} else {
+					backupPath, err := backupFile(accountInfoPath)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						os.Exit(1)
+					}
+					if err := saveAccountInfoToCSV(accountInfoPath, fixed); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						os.Exit(1)
+					}
+					warnf("Fixed %s: trimmed whitespace on %d rows, removed %d duplicate rows (backup: %s)\n", accountInfoPath, report.untrimmedRows, removed, backupPath)
+					report = check()
+				}
+			}
+
+			for _, problem := range report.problems {
+				fmt.Println(problem)
+			}
+			if len(report.problems) > 0 {
+				fmt.Fprintf(os.Stderr, "Found %d problems in %s\n", len(report.problems), accountInfoPath)
+				os.Exit(1)
+			}
+			warnf("No problems found in %s (%d accounts)\n", accountInfoPath, len(report.accounts))
+		},
+	}
+	validateCmd.Flags().StringVar(&validateFile, "file", "", "Path of the account info cache file to check (default ~/.aws/account_info)")
+	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Trim whitespace around fields and remove duplicate rows, then write the file back")
+	validateCmd.Flags().BoolVar(&keepFirst, "keep-first", false, "With --fix, keep the first row of a duplicated account ID instead of the last")
+
+	// Complete account names from the cached file only, never calling AWS
+	completeNames := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var path string
+		if len(filePaths) > 0 {
+			path = filePaths[0]
+		}
+		return completeAccountNames(path, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	for _, cmd := range []*cobra.Command{rootCmd, getCmd} {
+		cmd.ValidArgsFunction = completeNames
+		cmd.RegisterFlagCompletionFunc("name", completeNames)
+	}
+	envCmd.ValidArgsFunction = completeNames
+	for _, cmd := range []*cobra.Command{rootCmd, getCmd, listCmd} {
+		registerFlagCompletions(cmd)
+	}
+	var serveAddr string
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve account searches over HTTP from the account info cache",
+		Long: "Serve a read-only HTTP API over the account info cache, e.g. GET /accounts?name=prod&format=json.\n" +
+			"The cache is read at startup and, with --max-age, refreshed and read again at that interval.\n" +
+			"/accounts accepts the search, filter, sort and output flags of awsid as query parameters.\n" +
+			"/healthz reports whether the last load succeeded and /metrics exposes Prometheus metrics.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := applyConfigFile(cmd, configPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := setupDiagnostics(verbose, debug, quietFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			awsOptions := validatedAWSOptions()
+
+			// The server only starts with a readable cache; later reload failures keep the previous accounts
+			server := newAccountServer(func() ([]awsid.AccountInfo, error) { return readAccounts(awsOptions) })
+			if err := server.reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if maxAge > 0 {
+				go server.reloadEvery(ctx, maxAge)
+			}
+
+			httpServer := &http.Server{Addr: serveAddr, Handler: server.handler(), ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				httpServer.Shutdown(shutdownCtx)
+			}()
+
+			warnf("Serving account info on %s\n", serveAddr)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	addCacheFlags(serveCmd.Flags())
+	addLogFlags(serveCmd.Flags())
+	addRefreshFlags(serveCmd.Flags())
+
+	rootCmd.AddCommand(getCmd, listCmd, envCmd, updateCmd, migrateCmd, diffCmd, statsCmd, validateCmd, serveCmd, versionCmd, aliasCmd, historyCmd, newCompletionCmd(rootCmd))
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// newCompletionCmd returns the "completion" command that prints shell completion scripts
+func newCompletionCmd(rootCmd *cobra.Command) *cobra.Command {
+	// Replace cobra's default completion command with one that documents installation
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	return &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate a shell completion script",
+		Long: `Generate a completion script for the given shell and write it to stdout.
+
+Bash:
+  awsid completion bash > /etc/bash_completion.d/awsid
+  # or, for the current user only:
+  awsid completion bash > ~/.local/share/bash-completion/completions/awsid
+
+Zsh:
+  awsid completion zsh > "${fpath[1]}/_awsid"
+  # Completion must be enabled with "autoload -U compinit; compinit" in ~/.zshrc
+
+Fish:
+  awsid completion fish > ~/.config/fish/completions/awsid.fish
+
+PowerShell:
+  awsid completion powershell | Out-String | Invoke-Expression
+  # Add the line above to your PowerShell profile to load it in every session`,
+		Args:                  cobra.ExactArgs(1),
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			switch args[0] {
+			case "bash":
+				return rootCmd.GenBashCompletionV2(out, true)
+			case "zsh":
+				return rootCmd.GenZshCompletion(out)
+			case "fish":
+				return rootCmd.GenFishCompletion(out, true)
+			case "powershell":
+				return rootCmd.GenPowerShellCompletionWithDesc(out)
+			default:
+				return fmt.Errorf("unsupported shell \"%s\". Supported shells: bash, zsh, fish, powershell", args[0])
+			}
+		},
+	}
+}
+
+// registerFlagCompletions registers completion candidates for flags with a fixed set of values
+func registerFlagCompletions(cmd *cobra.Command) {
+	var statuses []string
+	for _, status := range types.AccountStatus("").Values() {
+		statuses = append(statuses, string(status))
+	}
+	fields := append(append(append([]string{}, awsid.AccountFields...), awsid.OptionalFields...), awsid.DerivedFields...)
+
+	candidates := map[string][]string{
+		"format":   awsid.Formats(),
+		"color":    ValidColorModes,
+		"group-by": ValidGroupFields,
+		"pager":    ValidPagerModes,
+	}
+	commaSeparated := map[string][]string{
+		"sort":      awsid.ValidSortFields,
+		"sort-desc": awsid.ValidSortFields,
+		"fields":    fields,
+		"status":    statuses,
+	}
+	for name, values := range candidates {
+		cmd.RegisterFlagCompletionFunc(name, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return values, cobra.ShellCompDirectiveNoFileComp
+		})
+	}
+	for name, values := range commaSeparated {
+		cmd.RegisterFlagCompletionFunc(name, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeCommaSeparated(values, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		})
+	}
+}
+
+// completeAccountNames returns the alias names in the cached account info file
+// that start with toComplete. Errors are ignored so completion never fails loudly.
+func completeAccountNames(filePath string, toComplete string) []string {
+	if filePath == "" {
+		var err error
+		filePath, err = defaultAccountInfoPath()
+		if err != nil {
+			return nil
+		}
+	}
+	// Never reach out to S3 while completing
+	if isS3URI(filePath) {
+		return nil
+	}
+	accounts, err := readAccountInfo(filePath, AWSOptions{})
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, account := range accounts {
+		if account.AliasName == "" || seen[account.AliasName] || !strings.HasPrefix(account.AliasName, toComplete) {
+			continue
+		}
+		seen[account.AliasName] = true
+		names = append(names, account.AliasName)
+	}
+	return names
+}
+
+// completeCommaSeparated completes the last item of a comma-separated value,
+// keeping the items already typed as a prefix
+func completeCommaSeparated(values []string, toComplete string) []string {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	completions := make([]string, 0, len(values))
+	for _, value := range values {
+		completions = append(completions, prefix+value)
+	}
+	return completions
+}
+
+// resolveFormatFlags resolves format conflicts and determines final format.
+// formatFlags maps the format selected by each individual flag (--json, --table, ...) to whether it is set.
+func resolveFormatFlags(formatOption string, formatFlags map[string]bool) (string, error) {
+	// Collect active format flags
+	var activeFormat string
+	activeFlags := 0
+	for format, active := range formatFlags {
+		if active {
+			activeFormat = format
+			activeFlags++
+		}
+	}
+
+	// Check for multiple individual format flags
+	if activeFlags > 1 {
+		return "", fmt.Errorf("multiple output format flags specified. Use only one format option")
+	}
+
+	// If --format is specified, validate and use it (takes priority)
+	if formatOption != "" {
+		if err := validateFormat(formatOption); err != nil {
+			return "", err
+		}
+		return formatOption, nil
+	}
+
+	// If individual format flag is specified, use it
+	if activeFlags == 1 {
+		return activeFormat, nil
+	}
+
+	// Default format (no flags specified - backward compatible behavior)
+	return "default", nil
+}
+
+// validateFormat validates the format string against the registered formats
+func validateFormat(format string) error {
+	supported := strings.Join(awsid.Formats(), ", ")
+	if format == "" {
+		return fmt.Errorf("output format cannot be empty. Supported formats: %s", supported)
+	}
+
+	if _, ok := awsid.LookupFormat(format); ok {
+		return nil
+	}
+	return fmt.Errorf("invalid output format \"%s\". Supported formats: %s", format, supported)
+}
+
+// resolveNames reads one alias name per line from r and writes a name,id line
+// to w for every exact match. Blank lines are skipped. Names without an exact
+// match are returned in input order.
+func resolveNames(r io.Reader, w io.Writer, accounts []awsid.AccountInfo, ignoreCase bool) ([]string, error) {
+	writer := csv.NewWriter(w)
+	var missing []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+		exactMatch, exact := awsid.SearchAccounts(accounts, awsid.Query{Name: name, IgnoreCase: ignoreCase})
+		if !exact {
+			missing = append(missing, name)
+			continue
+		}
+		for _, account := range exactMatch {
+			if err := writer.Write([]string{awsid.AccountName(account), account.AccountID}); err != nil {
+				return nil, fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	writer.Flush()
+	return missing, writer.Error()
+}
+
+// splitCommaSeparated splits a comma-separated value, trimming spaces and dropping empty entries
+func splitCommaSeparated(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// compileSearchRegex compiles the --regex pattern
+func compileSearchRegex(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	expr := pattern
+	if ignoreCase {
+		expr = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression \"%s\": %w", pattern, err)
+	}
+	return re, nil
+}
+
+// validateGlobPattern checks that pattern is a valid path.Match pattern
+func validateGlobPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob pattern \"%s\" (check for an unclosed [ or a trailing \\): %w", pattern, err)
+	}
+	return nil
+}
+
+// selectAccountInteractively lets the user pick one account with a fuzzy
+// finder on the terminal. It returns fuzzyfinder.ErrAbort when cancelled.
+func selectAccountInteractively(accounts []awsid.AccountInfo) (awsid.AccountInfo, error) {
+	if len(accounts) == 0 {
+		return awsid.AccountInfo{}, fmt.Errorf("no accounts to choose from")
+	}
+
+	index, err := fuzzyfinder.Find(accounts,
+		func(i int) string {
+			return fmt.Sprintf("%s  %s", awsid.AccountName(accounts[i]), accounts[i].ID)
+		},
+		fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+			if i < 0 {
+				return ""
+			}
+			var lines []string
+			for _, field := range awsid.AccountFields {
+				lines = append(lines, fmt.Sprintf("%s: %s", awsid.FieldHeaders[field], accounts[i].FieldValue(field)))
+			}
+			return strings.Join(lines, "\n")
+		}),
+	)
+	if err != nil {
+		return awsid.AccountInfo{}, err
+	}
+	return accounts[index], nil
+}
+
+// resolveStatusFlag parses and validates the comma-separated --status value
+func resolveStatusFlag(statusOption string) ([]string, error) {
+	if statusOption == "" {
+		return nil, nil
+	}
+
+	var validStatuses []string
+	for _, status := range types.AccountStatus("").Values() {
+		validStatuses = append(validStatuses, string(status))
+	}
+
+	var statuses []string
+	for _, status := range strings.Split(statusOption, ",") {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		valid := false
+		for _, validStatus := range validStatuses {
+			if status == validStatus {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid status \"%s\". Supported statuses: %s", status, strings.Join(validStatuses, ", "))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// resolveJoinedRange parses the --since and --until values. A date without a
+// time covers the whole day in local time. Zero times mean no bound.
+func resolveJoinedRange(sinceOption, untilOption string) (time.Time, time.Time, error) {
+	var since, until time.Time
+	var err error
+	if sinceOption != "" {
+		if since, err = parseTimeBound(sinceOption, false); err != nil {
+			return since, until, fmt.Errorf("invalid --since \"%s\": %w", sinceOption, err)
+		}
+	}
+	if untilOption != "" {
+		if until, err = parseTimeBound(untilOption, true); err != nil {
+			return since, until, fmt.Errorf("invalid --until \"%s\": %w", untilOption, err)
+		}
+	}
+	if !since.IsZero() && !until.IsZero() && since.After(until) {
+		return since, until, fmt.Errorf("--since %s is after --until %s", sinceOption, untilOption)
+	}
+	return since, until, nil
+}
+
+// parseTimeBound parses a YYYY-MM-DD date or an RFC 3339 timestamp. A date is
+// the start of the day in local time, or its last instant when endOfDay is set.
+func parseTimeBound(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date like 2024-01-31 or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// resolveTagFlags parses --tag key=value flags into values grouped by key
+func resolveTagFlags(tagOptions []string) (map[string][]string, error) {
+	tagFilter := make(map[string][]string)
+	for _, option := range tagOptions {
+		key, value, ok := strings.Cut(option, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag filter \"%s\". Use key=value", option)
+		}
+		tagFilter[key] = append(tagFilter[key], strings.TrimSpace(value))
+	}
+	return tagFilter, nil
+}
+
+// resolveFieldsFlag parses and validates the comma-separated --fields value
+func resolveFieldsFlag(fieldsOption string) ([]string, error) {
+	if fieldsOption == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, field := range strings.Split(fieldsOption, ",") {
+		field = strings.TrimSpace(field)
+		if err := validateField(field); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// validateField validates an output column name
+func validateField(field string) error {
+	validFields := append(append(append([]string{}, awsid.AccountFields...), awsid.OptionalFields...), awsid.DerivedFields...)
+	for _, valid := range validFields {
+		if field == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid field \"%s\". Supported fields: %s", field, strings.Join(validFields, ", "))
+}
+
+// SortInfo holds sort configuration. Keys are applied in order.
+type SortInfo struct {
+	Keys []awsid.SortKey
+}
+
+// resolveSortFlags validates and resolves sort configuration. natural selects
+// natural order for the name field.
+func resolveSortFlags(sortField, sortDesc string, natural bool) (*SortInfo, error) {
+	// Check for conflicting sort flags
+	if sortField != "" && sortDesc != "" {
+		return nil, fmt.Errorf("cannot specify both --sort and --sort-desc. Use only one sort option")
+	}
+
+	// No sort specified
+	if sortField == "" && sortDesc == "" {
+		if natural {
+			return nil, fmt.Errorf("--natural-sort requires sorting by name, e.g. --sort name")
+		}
+		return &SortInfo{}, nil
+	}
+
+	// Determine fields and default direction
+	fields := sortField
+	desc := false
+	if sortDesc != "" {
+		fields = sortDesc
+		desc = true
+	}
+
+	// Parse comma-separated fields with an optional :asc/:desc suffix each
+	sortInfo := &SortInfo{}
+	sortsByName := false
+	for _, spec := range strings.Split(fields, ",") {
+		field, direction, hasDirection := strings.Cut(strings.TrimSpace(spec), ":")
+		key := awsid.SortKey{Field: field, Descending: desc, Natural: natural}
+		if hasDirection {
+			switch direction {
+			case "asc":
+				key.Descending = false
+			case "desc":
+				key.Descending = true
+			default:
+				return nil, fmt.Errorf("invalid sort direction \"%s\" for field \"%s\". Use asc or desc", direction, field)
+			}
+		}
+
+		// Validate sort field
+		if err := validateSortField(key.Field); err != nil {
+			return nil, err
+		}
+		sortsByName = sortsByName || key.Field == "name"
+		sortInfo.Keys = append(sortInfo.Keys, key)
+	}
+
+	if natural && !sortsByName {
+		return nil, fmt.Errorf("--natural-sort requires sorting by name, e.g. --sort name")
+	}
+
+	return sortInfo, nil
+}
+
+// validateSortField validates the sort field name
+func validateSortField(field string) error {
+	for _, valid := range awsid.ValidSortFields {
+		if field == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid sort field \"%s\". Supported fields: %s", field, strings.Join(awsid.ValidSortFields, ", "))
+}
+
+// DefaultOutputManager is the built-in awsid.OutputManager used by the CLI.
+// All output is written to Writer so it can be captured, e.g. with a bytes.Buffer.
+type DefaultOutputManager struct {
+	Writer        io.Writer
+	Fields        []string       // Columns to output; all columns when empty
+	ReverseLookup bool           // Print the account name instead of the ID for exact matches
+	Template      string         // Go template used by the "template" format
+	Color         bool           // Colorize statuses in table output and highlight search matches
+	ExtraColumns  []string       // Optional columns appended to the default columns, e.g. "tags"
+	NoHeader      bool           // Omit the header row in CSV and TSV output
+	Delimiter     rune           // Field separator for CSV output; ',' when zero
+	Compact       bool           // Write JSON on a single line without indentation
+	MapMulti      bool           // Use ID arrays as values in the "map" format so duplicate names are kept
+	RoleName      string         // IAM role assumed in each account by the "aws-config" format
+	SourceProfile string         // source_profile of generated profiles; "default" when empty
+	Highlight     *regexp.Regexp // Search matches emphasized in default and table output when Color is set
+	HighlightAll  bool           // Highlight matches in every column instead of only the name
+	GroupBy       string         // Field to output accounts in groups by, e.g. "status"; no grouping when empty
+	MaxColWidth   int            // Maximum display width of table cells; no limit when zero
+	Meta          *OutputMeta    // Result metadata added to json and yaml output; omitted when nil
+}
+
+// defaultMaxColWidth is the default --max-col-width, enough for an ARN to stay recognizable
+const defaultMaxColWidth = 40
+
+var _ awsid.OutputManager = (*DefaultOutputManager)(nil)
+
+// NewOutputManager returns a DefaultOutputManager writing to w
+func NewOutputManager(w io.Writer) *DefaultOutputManager {
+	return &DefaultOutputManager{Writer: w}
+}
+
+// OutputAccounts writes accounts to stdout in the given format
+func OutputAccounts(accounts []awsid.AccountInfo, format string, isExactMatch bool) error {
+	return NewOutputManager(os.Stdout).Output(accounts, format, isExactMatch)
+}
+
+// columns returns the columns to output
+func (m *DefaultOutputManager) columns() []string {
+	if len(m.Fields) == 0 {
+		return append(append([]string{}, awsid.AccountFields...), m.ExtraColumns...)
+	}
+	return m.Fields
+}
+
+// row returns the values of the output columns for an account
+func (m *DefaultOutputManager) row(account awsid.AccountInfo) []string {
+	columns := m.columns()
+	values := make([]string, len(columns))
+	for i, field := range columns {
+		values[i] = account.FieldValue(field)
+	}
+	return values
+}
+
+// list wraps accounts for JSON/YAML output, keeping only the selected fields if any.
+// The list is never nil so an empty result encodes as [] rather than null.
+func (m *DefaultOutputManager) list(accounts []awsid.AccountInfo) interface{} {
+	if len(m.Fields) == 0 {
+		return AccountInfoList{Meta: m.Meta, Accounts: append([]awsid.AccountInfo{}, accounts...)}
+	}
+
+	selected := make([]selectedAccount, len(accounts))
+	for i, account := range accounts {
+		selected[i] = selectedAccount{fields: m.Fields, account: account}
+	}
+	return selectedAccountList{Meta: m.Meta, Accounts: selected}
+}
+
+// items returns the accounts to marshal as a bare array, limited to Fields when set.
+// The result is never nil so an empty result encodes as [] rather than null.
+func (m *DefaultOutputManager) items(accounts []awsid.AccountInfo) interface{} {
+	if len(m.Fields) == 0 {
+		return append([]awsid.AccountInfo{}, accounts...)
+	}
+
+	selected := make([]selectedAccount, len(accounts))
+	for i, account := range accounts {
+		selected[i] = selectedAccount{fields: m.Fields, account: account}
+	}
+	return selected
+}
+
+// Output outputs accounts using the specified format
+func (m *DefaultOutputManager) Output(accounts []awsid.AccountInfo, format string, isExactMatch bool) error {
+	if m.GroupBy != "" {
+		return m.outputGrouped(accounts, format)
+	}
+
+	// Formats selected by their own flags rather than --format
+	switch format {
+	case "console":
+		return m.outputConsoleURLs(accounts)
+	case "template":
+		return m.outputTemplate(accounts, m.Template)
+	case "default":
+		return m.outputDefault(accounts, isExactMatch)
+	}
+
+	output, ok := awsid.LookupFormat(format)
+	if !ok {
+		// Fallback to table format
+		return m.outputTable(accounts)
+	}
+	return output(managerWriter{m}, accounts)
+}
+
+// builtinFormats are the formats registered by the CLI, in --format help order
+var builtinFormats = []struct {
+	name   string
+	output func(*DefaultOutputManager, []awsid.AccountInfo) error
+}{
+	{"json", (*DefaultOutputManager).outputJSON},
+	{"json-array", (*DefaultOutputManager).outputJSONArray},
+	{"jsonl", (*DefaultOutputManager).outputJSONLines},
+	{"map", (*DefaultOutputManager).outputMap},
+	{"aws-config", (*DefaultOutputManager).outputAWSConfig},
+	{"table", (*DefaultOutputManager).outputTable},
+	{"csv", (*DefaultOutputManager).outputCSV},
+	{"yaml", (*DefaultOutputManager).outputYAML},
+	{"tsv", (*DefaultOutputManager).outputTSV},
+	{"markdown", (*DefaultOutputManager).outputMarkdown},
+	{"ids", (*DefaultOutputManager).outputIDsOnly},
+	{"names", (*DefaultOutputManager).outputNamesOnly},
+	{"sqlite", (*DefaultOutputManager).outputSQLite},
+	{"xlsx", (*DefaultOutputManager).outputXLSX},
+}
+
+func init() {
+	for _, format := range builtinFormats {
+		awsid.RegisterFormat(format.name, managerFormat(format.output))
+	}
+}
+
+// managerWriter is the writer Output passes to registered formats. It lets
+// built-in formats use the options of the manager it writes for.
+type managerWriter struct {
+	m *DefaultOutputManager
+}
+
+func (w managerWriter) Write(p []byte) (int, error) {
+	return w.m.Writer.Write(p)
+}
+
+// managerFormat adapts a DefaultOutputManager method to an awsid.FormatFunc.
+// Given a managerWriter it runs with that manager's options, otherwise with
+// the default options on w.
+func managerFormat(output func(*DefaultOutputManager, []awsid.AccountInfo) error) awsid.FormatFunc {
+	return func(w io.Writer, accounts []awsid.AccountInfo) error {
+		if mw, ok := w.(managerWriter); ok {
+			return output(mw.m, accounts)
+		}
+		return output(NewOutputManager(w), accounts)
+	}
+}
+
+// ValidGroupFields lists the fields accepted by --group-by
+var ValidGroupFields = []string{"status", "joined_method", "ou"}
+
+// validateGroupBy validates the --group-by value; empty means no grouping
+func validateGroupBy(field string) error {
+	if field == "" {
+		return nil
+	}
+	for _, valid := range ValidGroupFields {
+		if field == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid group-by field \"%s\". Supported fields: %s", field, strings.Join(ValidGroupFields, ", "))
+}
+
+// groupKey returns the group of an account for a --group-by field. The ou
+// group is the OU path. Accounts without a value are grouped as "(none)".
+func groupKey(account awsid.AccountInfo, field string) string {
+	var key string
+	switch field {
+	case "status":
+		key = account.Status
+	case "joined_method":
+		key = account.JoinedMethod
+	case "ou":
+		key = account.OUPath
+	}
+	if key == "" {
+		return "(none)"
+	}
+	return key
+}
+
+// outputGrouped writes accounts grouped by GroupBy, with groups sorted by name
+// and accounts keeping their order within a group. JSON and YAML formats write
+// one object keyed by group; other formats write a "== GROUP (count) =="
+// heading before each group.
+func (m *DefaultOutputManager) outputGrouped(accounts []awsid.AccountInfo, format string) error {
+	groups := make(map[string][]awsid.AccountInfo)
+	var keys []string
+	for _, account := range accounts {
+		key := groupKey(account, m.GroupBy)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], account)
+	}
+	sort.Strings(keys)
+
+	switch format {
+	case "json", "json-array":
+		object := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			object[key] = m.items(groups[key])
+		}
+		jsonData, err := m.marshalJSON(object)
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(m.Writer, string(jsonData))
+		return err
+	case "yaml":
+		object := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			object[key] = m.items(groups[key])
+		}
+		yamlData, err := yaml.Marshal(object)
+		if err != nil {
+			return fmt.Errorf("failed to create YAML: %w", err)
+		}
+		_, err = fmt.Fprint(m.Writer, string(yamlData))
+		return err
+	}
+
+	group := *m
+	group.GroupBy = ""
+	for i, key := range keys {
+		if i > 0 {
+			if _, err := fmt.Fprintln(m.Writer); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(m.Writer, "== %s (%d) ==\n", key, len(groups[key])); err != nil {
+			return err
+		}
+		// Grouped output always lists accounts, even for an exact match
+		if err := group.Output(groups[key], format, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputDefault shows account IDs for exact matches, detailed info for partial matches
+func (m *DefaultOutputManager) outputDefault(accounts []awsid.AccountInfo, isExactMatch bool) error {
+	if isExactMatch && len(accounts) > 0 && m.ReverseLookup {
+		_, err := fmt.Fprintln(m.Writer, awsid.AccountName(accounts[0]))
+		return err
+	}
+	// Several exact matches still print a single ID, as scripts expect one line.
+	// Never print an empty line, which $(awsid name) would silently accept.
+	if isExactMatch && len(accounts) > 0 {
+		id := accounts[0].AccountID
+		if id == "" {
+			id = accounts[0].ID
+		}
+		if id == "" {
+			return fmt.Errorf("account %s has no account ID", awsid.AccountName(accounts[0]))
+		}
+		_, err := fmt.Fprintln(m.Writer, id)
+		return err
+	}
+
+	for _, account := range accounts {
+		if _, err := fmt.Fprintf(m.Writer, "ID: %s | ARN: %s | Email: %s | Name: %s | Status: %s | Method: %s | Joined: %s\n",
+			m.highlight("id", account.ID), m.highlight("arn", account.Arn), m.highlight("email", account.Email), m.highlight("name", account.Name),
+			m.highlight("status", account.Status), m.highlight("joined_method", account.JoinedMethod), m.highlight("joined_timestamp", account.JoinedTimestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatFlagNames lists flags that select an output format. A format from the
+// config file is ignored when any of them is given on the command line.
+var formatFlagNames = []string{"format", "json", "json-array", "table", "csv", "yaml", "ids-only", "names-only", "template", "console"}
+
+// applyConfigFile sets flags from a YAML config file unless they were given on
+// the command line. Keys are flag names with underscores or hyphens, e.g.
+// "format: table" or "max_age: 1h". A missing default config file is ignored.
+func applyConfigFile(cmd *cobra.Command, path string) error {
+	explicit := path != ""
+	if !explicit {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(homeDir, ".awsid.yaml")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	formatGiven := false
+	for _, name := range formatFlagNames {
+		if cmd.Flags().Changed(name) {
+			formatGiven = true
+		}
+	}
+
+	for key, value := range values {
+		name := strings.ReplaceAll(key, "_", "-")
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil && cmd.Root().Flags().Lookup(name) != nil {
+			continue // Valid key for another command, e.g. "format" for update
+		}
+		if flag == nil || name == "config" {
+			return fmt.Errorf("unknown key \"%s\" in config file %s", key, path)
+		}
+		if flag.Changed || (formatGiven && name == "format") {
+			continue // Command line flags take priority
+		}
+
+		// Lists are set item by item for repeatable flags and comma-joined otherwise
+		var strs []string
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			if flag.Value.Type() == "stringArray" {
+				strs = items
+			} else {
+				strs = []string{strings.Join(items, ",")}
+			}
+		} else {
+			strs = []string{fmt.Sprint(value)}
+		}
+		for _, str := range strs {
+			if err := cmd.Flags().Set(name, str); err != nil {
+				return fmt.Errorf("invalid value for \"%s\" in config file %s: %w", key, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultAccountInfoPath returns ~/.aws/account_info
+func defaultAccountInfoPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".aws", "account_info"), nil
+}
+
+// resolveAccountInfoPaths returns the cache files to read: the --file
+// values, or ~/.aws/account_info when none is given
+func resolveAccountInfoPaths(filePaths []string) ([]string, error) {
+	if len(filePaths) > 0 {
+		return filePaths, nil
+	}
+	defaultPath, err := defaultAccountInfoPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return []string{defaultPath}, nil
+}
+
+// readAccountInfo reads the account info cache from a local file, or from S3
+// when filePath is an s3://bucket/key URI. opts is only used for S3.
+func readAccountInfo(filePath string, opts AWSOptions) ([]awsid.AccountInfo, error) {
+	if isS3URI(filePath) {
+		return readAccountInfoFromS3(filePath, opts)
+	}
+	if isSQLiteFile(filePath) {
+		return readAccountInfoFromSQLite(filePath)
+	}
+	return awsid.ReadAccountInfo(filePath)
+}
+
+func (m *DefaultOutputManager) outputJSON(accounts []awsid.AccountInfo) error {
+	jsonData, err := m.marshalJSON(m.list(accounts))
+	if err != nil {
+		return fmt.Errorf("failed to create JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(m.Writer, string(jsonData))
+	return err
+}
+
+// marshalJSON encodes v indented with four spaces, or on one line when Compact is set
+func (m *DefaultOutputManager) marshalJSON(v interface{}) ([]byte, error) {
+	if m.Compact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "    ")
+}
+
+// outputJSONArray writes accounts as a top-level JSON array without the
+// account_info wrapper, even for a single account
+func (m *DefaultOutputManager) outputJSONArray(accounts []awsid.AccountInfo) error {
+	jsonData, err := m.marshalJSON(m.items(accounts))
+	if err != nil {
+		return fmt.Errorf("failed to create JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(m.Writer, string(jsonData))
+	return err
+}
+
+// outputMap writes a single JSON object mapping account names to IDs. Later
+// accounts with the same name overwrite earlier ones unless MapMulti is set,
+// in which case every value is an array of IDs.
+func (m *DefaultOutputManager) outputMap(accounts []awsid.AccountInfo) error {
+	var value interface{}
+	if m.MapMulti {
+		ids := make(map[string][]string)
+		for _, account := range accounts {
+			name := awsid.AccountName(account)
+			ids[name] = append(ids[name], account.ID)
+		}
+		value = ids
+	} else {
+		ids := make(map[string]string)
+		for _, account := range accounts {
+			ids[awsid.AccountName(account)] = account.ID
+		}
+		value = ids
+	}
+
+	jsonData, err := m.marshalJSON(value)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(m.Writer, string(jsonData))
+	return err
+}
+
+// outputAWSConfig writes one ~/.aws/config profile block per account that assumes
+// RoleName in the account from SourceProfile. The output can be appended to
+// ~/.aws/config as is.
+func (m *DefaultOutputManager) outputAWSConfig(accounts []awsid.AccountInfo) error {
+	sourceProfile := m.SourceProfile
+	if sourceProfile == "" {
+		sourceProfile = "default"
+	}
+
+	for i, account := range accounts {
+		if i > 0 {
+			if _, err := fmt.Fprintln(m.Writer); err != nil {
+				return err
+			}
+		}
+		roleARN := fmt.Sprintf("arn:%s:iam::%s:role/%s", accountPartition(account), account.ID, m.RoleName)
+		if _, err := fmt.Fprintf(m.Writer, "[profile %s]\nrole_arn = %s\nsource_profile = %s\n", profileName(account), roleARN, sourceProfile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputConsoleURLs writes the switch role console URL of each account, one per line
+func (m *DefaultOutputManager) outputConsoleURLs(accounts []awsid.AccountInfo) error {
+	for _, account := range accounts {
+		if _, err := fmt.Fprintln(m.Writer, consoleURL(account, m.RoleName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consoleSignInHosts maps partitions to their console sign-in host
+var consoleSignInHosts = map[string]string{
+	"aws":        "signin.aws.amazon.com",
+	"aws-us-gov": "signin.amazonaws-us-gov.com",
+	"aws-cn":     "signin.amazonaws.cn",
+}
+
+// consoleURL builds the AWS Management Console URL that switches to roleName in the account
+func consoleURL(account awsid.AccountInfo, roleName string) string {
+	host, ok := consoleSignInHosts[accountPartition(account)]
+	if !ok {
+		host = consoleSignInHosts["aws"]
+	}
+	query := url.Values{}
+	query.Set("account", account.ID)
+	query.Set("roleName", roleName)
+	query.Set("displayName", awsid.AccountName(account))
+	return fmt.Sprintf("https://%s/switchrole?%s", host, query.Encode())
+}
+
+// openBrowser opens target in the default browser of the OS
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		// "start" would need cmd.exe, which treats & in the URL as a command separator
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
+// profileName turns an account name into an AWS CLI profile name by replacing
+// runs of whitespace and other unsafe characters with a hyphen. The account ID
+// is used when nothing is left.
+func profileName(account awsid.AccountInfo) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range awsid.AccountName(account) {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+		} else {
+			pendingHyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return account.ID
+	}
+	return b.String()
+}
+
+// accountPartition returns the partition from the account ARN, e.g. "aws-us-gov",
+// defaulting to "aws" for accounts without an ARN
+func accountPartition(account awsid.AccountInfo) string {
+	parts := strings.SplitN(account.Arn, ":", 3)
+	if len(parts) == 3 && parts[0] == "arn" && parts[1] != "" {
+		return parts[1]
+	}
+	return "aws"
+}
+
+// outputJSONLines streams one compact JSON object per account (JSON Lines / NDJSON)
+func (m *DefaultOutputManager) outputJSONLines(accounts []awsid.AccountInfo) error {
+	encoder := json.NewEncoder(m.Writer)
+	for _, account := range accounts {
+		var err error
+		if len(m.Fields) > 0 {
+			err = encoder.Encode(selectedAccount{fields: m.Fields, account: account})
+		} else {
+			err = encoder.Encode(account)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *DefaultOutputManager) outputYAML(accounts []awsid.AccountInfo) error {
+	yamlData, err := yaml.Marshal(m.list(accounts))
+	if err != nil {
+		return fmt.Errorf("failed to create YAML: %w", err)
+	}
+
+	_, err = fmt.Fprint(m.Writer, string(yamlData))
+	return err
+}
+
+// outputIDsOnly writes each account ID on its own line without any decoration
+func (m *DefaultOutputManager) outputIDsOnly(accounts []awsid.AccountInfo) error {
+	for _, account := range accounts {
+		if _, err := fmt.Fprintln(m.Writer, account.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputNamesOnly writes each account name on its own line without any decoration
+func (m *DefaultOutputManager) outputNamesOnly(accounts []awsid.AccountInfo) error {
+	for _, account := range accounts {
+		if _, err := fmt.Fprintln(m.Writer, awsid.AccountName(account)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTemplate parses a user-supplied --template string
+func parseTemplate(tmplStr string) (*template.Template, error) {
+	tmpl, err := template.New("account").Parse(tmplStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// outputTemplate renders each account with the template, one per line
+func (m *DefaultOutputManager) outputTemplate(accounts []awsid.AccountInfo, tmplStr string) error {
+	tmpl, err := parseTemplate(tmplStr)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if err := tmpl.Execute(m.Writer, account); err != nil {
+			return fmt.Errorf("failed to execute template: %w", err)
+		}
+		if _, err := fmt.Fprintln(m.Writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *DefaultOutputManager) outputTable(accounts []awsid.AccountInfo) error {
+	var options []tablewriter.Option
+	if m.MaxColWidth > 0 {
+		// Widths are measured in terminal cells, so CJK characters count as two
+		options = append(options, tablewriter.WithRowAutoWrap(tw.WrapTruncate), tablewriter.WithRowMaxWidth(m.MaxColWidth))
+	}
+	table := tablewriter.NewTable(m.Writer, options...)
+	var headers []any
+	for _, field := range m.columns() {
+		headers = append(headers, awsid.FieldHeaders[field])
+	}
+	table.Header(headers...)
+
+	for _, account := range accounts {
+		row := m.row(account)
+		if m.Color {
+			for i, field := range m.columns() {
+				if field == "status" {
+					row[i] = colorizeStatus(row[i])
+				} else {
+					row[i] = m.highlight(field, row[i])
+				}
+			}
+		}
+		if err := table.Append(row); err != nil {
+			return fmt.Errorf("failed to append table row: %w", err)
+		}
+	}
+
+	return table.Render()
+}
+
+// outputMarkdown writes accounts as a GitHub-flavored Markdown table
+func (m *DefaultOutputManager) outputMarkdown(accounts []awsid.AccountInfo) error {
+	columns := m.columns()
+	headers := make([]string, len(columns))
+	separators := make([]string, len(columns))
+	for i, field := range columns {
+		headers[i] = escapeMarkdownCell(awsid.FieldHeaders[field])
+		separators[i] = "---"
+	}
+	if _, err := fmt.Fprintf(m.Writer, "| %s |\n|%s|\n", strings.Join(headers, " | "), strings.Join(separators, "|")); err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		row := m.row(account)
+		for i, value := range row {
+			row[i] = escapeMarkdownCell(value)
+		}
+		if _, err := fmt.Fprintf(m.Writer, "| %s |\n", strings.Join(row, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeMarkdownCell escapes pipes so a value stays within its table cell
+func escapeMarkdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+// xlsxSheetName is the name of the worksheet written by --format xlsx
+const xlsxSheetName = "Accounts"
+
+// outputXLSX writes accounts as an Excel workbook with a bold header row.
+// Values are written as text so account IDs keep their leading zeros, and
+// SUSPENDED accounts are highlighted in red when the status column is output.
+func (m *DefaultOutputManager) outputXLSX(accounts []awsid.AccountInfo) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", xlsxSheetName); err != nil {
+		return fmt.Errorf("failed to create worksheet: %w", err)
+	}
+
+	columns := m.columns()
+	row := 1
+	if !m.NoHeader {
+		headers := make([]string, len(columns))
+		for i, field := range columns {
+			headers[i] = awsid.FieldHeaders[field]
+		}
+		if err := f.SetSheetRow(xlsxSheetName, "A1", &headers); err != nil {
+			return fmt.Errorf("failed to write XLSX header: %w", err)
+		}
+		bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+		if err != nil {
+			return err
+		}
+		if err := f.SetRowStyle(xlsxSheetName, 1, 1, bold); err != nil {
+			return err
+		}
+		row++
+	}
+
+	firstDataRow := row
+	for _, account := range accounts {
+		values := m.row(account)
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(xlsxSheetName, cell, &values); err != nil {
+			return fmt.Errorf("failed to write XLSX row: %w", err)
+		}
+		row++
+	}
+
+	for i, field := range columns {
+		if field != "status" || row == firstDataRow {
+			continue
+		}
+		if err := highlightSuspended(f, i+1, firstDataRow, row-1); err != nil {
+			return fmt.Errorf("failed to format the status column: %w", err)
+		}
+	}
+
+	return f.Write(m.Writer)
+}
+
+// highlightSuspended adds a conditional format giving SUSPENDED cells of the
+// given column a red background over rows first to last
+func highlightSuspended(f *excelize.File, column, first, last int) error {
+	from, err := excelize.CoordinatesToCellName(column, first)
+	if err != nil {
+		return err
+	}
+	to, err := excelize.CoordinatesToCellName(column, last)
+	if err != nil {
+		return err
+	}
+	red, err := f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+	})
+	if err != nil {
+		return err
+	}
+	return f.SetConditionalFormat(xlsxSheetName, from+":"+to, []excelize.ConditionalFormatOptions{
+		{Type: "cell", Criteria: "==", Format: &red, Value: `"SUSPENDED"`},
+	})
+}
+
+// ValidColorModes lists the values accepted by --color
+var ValidColorModes = []string{"auto", "always", "never"}
+
+// validateColorMode validates the --color value
+func validateColorMode(mode string) error {
+	for _, valid := range ValidColorModes {
+		if mode == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid color mode \"%s\". Supported modes: %s", mode, strings.Join(ValidColorModes, ", "))
+}
+
+// resolveDelimiter validates the --delimiter value, which must be a single
+// character usable by encoding/csv
+func resolveDelimiter(value string) (rune, error) {
+	runes := []rune(value)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("invalid delimiter \"%s\". Specify a single character", value)
+	}
+	delimiter := runes[0]
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+		return 0, fmt.Errorf("invalid delimiter %q. Quotes and line breaks cannot be used", value)
+	}
+	return delimiter, nil
+}
+
+// shouldColorize decides whether to emit color escape sequences to the writer.
+// In auto mode colors are only used when writing to a terminal.
+func shouldColorize(mode string, w io.Writer) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd())
+}
+
+// highlight emphasizes matches of the search in a column value. Only the name
+// column is highlighted unless HighlightAll is set, and nothing is without Color.
+func (m *DefaultOutputManager) highlight(field, value string) string {
+	if !m.Color || m.Highlight == nil || (field != "name" && !m.HighlightAll) {
+		return value
+	}
+	c := color.New(color.Bold, color.FgCyan)
+	c.EnableColor()
+	return m.Highlight.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "" {
+			return match
+		}
+		return c.Sprint(match)
+	})
+}
+
+// literalRegexp returns a regexp matching any of the terms literally
+func literalRegexp(terms []string, ignoreCase bool) *regexp.Regexp {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	expr := strings.Join(quoted, "|")
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+// affixRegexp returns a regexp matching the prefix at the start and the suffix
+// at the end of a name
+func affixRegexp(prefix, suffix string, ignoreCase bool) *regexp.Regexp {
+	var parts []string
+	if prefix != "" {
+		parts = append(parts, "^"+regexp.QuoteMeta(prefix))
+	}
+	if suffix != "" {
+		parts = append(parts, regexp.QuoteMeta(suffix)+"$")
+	}
+	expr := strings.Join(parts, "|")
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+// ValidPagerModes lists the values accepted by --pager
+var ValidPagerModes = []string{"auto", "always", "never"}
+
+// validatePagerMode validates the --pager value
+func validatePagerMode(mode string) error {
+	for _, valid := range ValidPagerModes {
+		if mode == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid pager mode \"%s\". Supported modes: %s", mode, strings.Join(ValidPagerModes, ", "))
+}
+
+// usePager reports whether output to w may go through a pager. Pipes,
+// redirects and --output files are never paged, even with "always".
+func usePager(mode string, w io.Writer) bool {
+	if mode == "never" || w != os.Stdout {
+		return false
+	}
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+// writeWithPager writes data to stdout through $PAGER, or less -FRX when it is
+// unset. In auto mode data that fits in the terminal is written directly.
+// When the pager cannot be started data is written directly as well.
+func writeWithPager(mode string, data []byte) error {
+	if mode == "auto" {
+		_, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil || bytes.Count(data, []byte("\n")) < height {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+	}
+
+	args := strings.Fields(os.Getenv("PAGER"))
+	if len(args) == 0 {
+		args = []string{"less", "-FRX"}
+	}
+	pager := exec.Command(args[0], args[1:]...)
+	pager.Stdin = bytes.NewReader(data)
+	pager.Stdout = os.Stdout
+	pager.Stderr = os.Stderr
+	if err := pager.Start(); err != nil {
+		logger.Info("failed to start pager, writing output directly", "pager", args[0], "error", err)
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	// The pager exits non-zero when closed early, which is not an error here
+	pager.Wait()
+	return nil
+}
+
+// colorizeStatus colors a status: ACTIVE green, SUSPENDED red, anything else yellow
+func colorizeStatus(status string) string {
+	var c *color.Color
+	switch status {
+	case "ACTIVE":
+		c = color.New(color.FgGreen)
+	case "SUSPENDED":
+		c = color.New(color.FgRed)
+	default:
+		c = color.New(color.FgYellow)
+	}
+	c.EnableColor()
+	return c.Sprint(status)
+}
+
+func (m *DefaultOutputManager) outputCSV(accounts []awsid.AccountInfo) error {
+	if m.Delimiter != 0 {
+		return m.outputDelimited(accounts, m.Delimiter)
+	}
+	return m.outputDelimited(accounts, ',')
+}
+
+// outputTSV writes the same columns as outputCSV separated by tabs
+func (m *DefaultOutputManager) outputTSV(accounts []awsid.AccountInfo) error {
+	return m.outputDelimited(accounts, '\t')
+}
+
+// outputDelimited writes accounts as delimiter-separated values with a header row
+// unless NoHeader is set
+func (m *DefaultOutputManager) outputDelimited(accounts []awsid.AccountInfo, comma rune) error {
+	writer := csv.NewWriter(m.Writer)
+	writer.Comma = comma
+
+	// Write header
+	if !m.NoHeader {
+		if err := writer.Write(m.columns()); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	// Write data
+	for _, account := range accounts {
+		if err := writer.Write(m.row(account)); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// isCacheFresh reports whether the cached file was modified within maxAge
+func isCacheFresh(filePath string, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	return info.ModTime().After(time.Now().Add(-maxAge))
+}
+
+// logger writes diagnostic logs to stderr. It discards everything unless
+// --verbose or --debug is given.
+var logger = slog.New(slog.DiscardHandler)
+
+// newLogger returns a text logger on stderr at info level for verbose and
+// debug level for debug, or a logger that discards everything
+func newLogger(verbose, debug bool) *slog.Logger {
+	switch {
+	case debug:
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	case verbose:
+		return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	default:
+		return slog.New(slog.DiscardHandler)
+	}
+}
+
+// quiet suppresses warnings and informational messages written with warnf
+var quiet bool
+
+// warnf writes a warning or informational message to stderr unless --quiet is given
+func warnf(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// setupDiagnostics configures the logger and warnings from --verbose, --debug and --quiet
+func setupDiagnostics(verbose, debug, quietFlag bool) error {
+	if quietFlag && (verbose || debug) {
+		return fmt.Errorf("cannot specify both --quiet and --verbose or --debug")
+	}
+	quiet = quietFlag
+	logger = newLogger(verbose, debug)
+	return nil
+}
+
+// AWSOptions holds settings used to build the AWS client configuration
+type AWSOptions struct {
+	Profile string // Shared config profile; default credential chain when empty
+	Region  string // Region for the Organizations API; defaultRegion when empty
+	// RoleARN is assumed via STS before calling Organizations when set
+	RoleARN    string
+	ExternalID string
+	MaxRetries int // Retries after the first attempt, with exponential backoff
+	// Timeout bounds the whole update, including credential lookups; none when 0
+	Timeout time.Duration
+	// EndpointURL replaces the AWS endpoint of every service, e.g. for LocalStack.
+	// When empty, AWS_ENDPOINT_URL and the other SDK settings apply.
+	EndpointURL string
+}
+
+// validateAWSOptions checks option combinations before any AWS call is made
+func validateAWSOptions(opts AWSOptions) error {
+	if opts.ExternalID != "" && opts.RoleARN == "" {
+		return fmt.Errorf("--external-id requires --role-arn")
+	}
+	if opts.MaxRetries < 0 {
+		return fmt.Errorf("--max-retries must be 0 or greater")
+	}
+	if opts.EndpointURL != "" {
+		endpoint, err := url.Parse(opts.EndpointURL)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("invalid --endpoint-url \"%s\". Use an http:// or https:// URL", opts.EndpointURL)
+		}
+	}
+	if opts.Timeout < 0 {
+		return fmt.Errorf("--timeout must be 0 or greater")
+	}
+	return nil
+}
+
+const (
+	// defaultRegion is used for the Organizations API unless --region is given
+	defaultRegion = "us-east-1"
+	// defaultMaxRetries is the default number of retries for AWS API calls
+	defaultMaxRetries = 5
+	// maxRetryBackoff caps the exponential backoff delay between retries
+	maxRetryBackoff = 20 * time.Second
+	// defaultTimeout is the default deadline for updating from AWS
+	defaultTimeout = 30 * time.Second
+)
+
+// loadAWSConfig loads the AWS configuration for the given options
+func loadAWSConfig(ctx context.Context, opts AWSOptions) (aws.Config, error) {
+	// Organizations is global but requires a region
+	region := opts.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	loadOptions := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		// Retry throttling (TooManyRequestsException) and transient errors with exponential backoff
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = opts.MaxRetries + 1
+				o.Backoff = retry.NewExponentialJitterBackoff(maxRetryBackoff)
+			})
+		}),
+	}
+	if opts.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(opts.Profile))
+	}
+	if opts.EndpointURL != "" {
+		loadOptions = append(loadOptions, config.WithBaseEndpoint(opts.EndpointURL))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return cfg, err
+	}
+
+	// Swap in credentials from the assumed role
+	if opts.RoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if opts.ExternalID != "" {
+				o.ExternalID = aws.String(opts.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return cfg, nil
+}
+
+// timestampLayout is the format of joined timestamps written to account_info
+const timestampLayout = "2006-01-02T15:04:05.000000-07:00"
+
+// FetchOptions selects additional data fetched for each account
+type FetchOptions struct {
+	WithTags bool // Call ListTagsForResource for each account
+	WithOU   bool // Resolve the parent OU of each account via ListParents
+}
+
+// needsMigration reports whether any account was read from the old
+// alias_name,account_id format
+func needsMigration(accounts []awsid.AccountInfo) bool {
+	for _, account := range accounts {
+		if account.Legacy() {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAccountDetails fills empty fields of local accounts from remote accounts
+// with the same ID, keeping the local alias names. It returns the number of
+// accounts that were found in remote.
+func mergeAccountDetails(local []awsid.AccountInfo, remote []awsid.AccountInfo) int {
+	byID := make(map[string]awsid.AccountInfo, len(remote))
+	for _, account := range remote {
+		byID[account.ID] = account
+	}
+
+	merged := 0
+	for i := range local {
+		account := &local[i]
+		source, ok := byID[account.ID]
+		if !ok {
+			continue
+		}
+		merged++
+		for _, field := range []struct {
+			dst *string
+			src string
+		}{
+			{&account.Arn, source.Arn},
+			{&account.Email, source.Email},
+			{&account.Name, source.Name},
+			{&account.Status, source.Status},
+			{&account.JoinedMethod, source.JoinedMethod},
+			{&account.JoinedTimestamp, source.JoinedTimestamp},
+			{&account.OUID, source.OUID},
+			{&account.OUName, source.OUName},
+			{&account.OUPath, source.OUPath},
+			{&account.OUIDPath, source.OUIDPath},
+		} {
+			if *field.dst == "" {
+				*field.dst = field.src
+			}
+		}
+		if account.Tags == nil {
+			account.Tags = source.Tags
+		}
+	}
+	return merged
+}
+
+// backupFile copies path to path.bak, overwriting any previous backup
+func backupFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	backupPath := path + ".bak"
+	if err := os.WriteFile(backupPath, data, cacheFileMode); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+	}
+	// WriteFile keeps the mode of an existing backup
+	if err := os.Chmod(backupPath, cacheFileMode); err != nil {
+		return "", fmt.Errorf("failed to set permissions on %s: %w", backupPath, err)
+	}
+	return backupPath, nil
+}
+
+// FieldChange is a field whose value differs between two snapshots of an account
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// AccountChange lists the changed fields of an account present in both snapshots
+type AccountChange struct {
+	ID      string        `json:"id"`
+	Name    string        `json:"name"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// AccountInfoDiff is the difference between two account info snapshots, keyed by account ID
+type AccountInfoDiff struct {
+	Added   []awsid.AccountInfo `json:"added"`
+	Removed []awsid.AccountInfo `json:"removed"`
+	Changed []AccountChange     `json:"changed"`
+}
+
+// diffAccountInfo compares two snapshots by account ID. Added and changed
+// accounts follow the order of newAccounts, removed ones the order of oldAccounts.
+func diffAccountInfo(oldAccounts, newAccounts []awsid.AccountInfo) AccountInfoDiff {
+	diff := AccountInfoDiff{Added: []awsid.AccountInfo{}, Removed: []awsid.AccountInfo{}, Changed: []AccountChange{}}
+
+	oldByID := make(map[string]awsid.AccountInfo, len(oldAccounts))
+	for _, account := range oldAccounts {
+		oldByID[account.ID] = account
+	}
+	newByID := make(map[string]awsid.AccountInfo, len(newAccounts))
+	for _, account := range newAccounts {
+		newByID[account.ID] = account
+	}
+
+	fields := append(append([]string{}, awsid.AccountFields...), awsid.OptionalFields...)
+	for _, account := range newAccounts {
+		previous, ok := oldByID[account.ID]
+		if !ok {
+			diff.Added = append(diff.Added, account)
+			continue
+		}
+		var changes []FieldChange
+		for _, field := range fields {
+			if oldValue, newValue := previous.FieldValue(field), account.FieldValue(field); oldValue != newValue {
+				changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+			}
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, AccountChange{ID: account.ID, Name: awsid.AccountName(account), Changes: changes})
+		}
+	}
+	for _, account := range oldAccounts {
+		if _, ok := newByID[account.ID]; !ok {
+			diff.Removed = append(diff.Removed, account)
+		}
+	}
+	return diff
+}
+
+// writeDiffText writes a diff as "+ added", "- removed" and "~ changed" lines,
+// with one indented "field: old -> new" line per changed field
+func writeDiffText(w io.Writer, diff AccountInfoDiff, colorize bool) error {
+	paint := func(attr color.Attribute, text string) string {
+		if !colorize {
+			return text
+		}
+		c := color.New(attr)
+		c.EnableColor()
+		return c.Sprint(text)
+	}
+
+	for _, account := range diff.Added {
+		if _, err := fmt.Fprintln(w, paint(color.FgGreen, fmt.Sprintf("+ %s %s", account.ID, awsid.AccountName(account)))); err != nil {
+			return err
+		}
+	}
+	for _, account := range diff.Removed {
+		if _, err := fmt.Fprintln(w, paint(color.FgRed, fmt.Sprintf("- %s %s", account.ID, awsid.AccountName(account)))); err != nil {
+			return err
+		}
+	}
+	for _, change := range diff.Changed {
+		if _, err := fmt.Fprintln(w, paint(color.FgYellow, fmt.Sprintf("~ %s %s", change.ID, change.Name))); err != nil {
+			return err
+		}
+		for _, field := range change.Changes {
+			if _, err := fmt.Fprintf(w, "    %s: %s -> %s\n", field.Field, field.Old, field.New); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDiffJSON writes a diff as an indented JSON object
+func writeDiffJSON(w io.Writer, diff AccountInfoDiff) error {
+	jsonData, err := json.MarshalIndent(diff, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to create JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+// StatCount is the number of accounts sharing a field value
+type StatCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// AccountStats summarizes the accounts in a cache file
+type AccountStats struct {
+	Total        int         `json:"total"`
+	Status       []StatCount `json:"status"`
+	JoinedMethod []StatCount `json:"joined_method"`
+	EmailDomain  []StatCount `json:"email_domain,omitempty"`
+}
+
+// computeAccountStats counts accounts by status and joined method, and by
+// email domain when byEmailDomain is set
+func computeAccountStats(accounts []awsid.AccountInfo, byEmailDomain bool) AccountStats {
+	stats := AccountStats{
+		Total:        len(accounts),
+		Status:       countAccountsBy(accounts, func(a awsid.AccountInfo) string { return a.Status }),
+		JoinedMethod: countAccountsBy(accounts, func(a awsid.AccountInfo) string { return a.JoinedMethod }),
+	}
+	if byEmailDomain {
+		stats.EmailDomain = countAccountsBy(accounts, func(a awsid.AccountInfo) string {
+			if at := strings.LastIndex(a.Email, "@"); at >= 0 {
+				return strings.ToLower(a.Email[at+1:])
+			}
+			return ""
+		})
+	}
+	return stats
+}
+
+// countAccountsBy counts accounts per key, most frequent first and then by key.
+// Accounts with an empty key are counted as "(none)".
+func countAccountsBy(accounts []awsid.AccountInfo, key func(awsid.AccountInfo) string) []StatCount {
+	counts := make(map[string]int)
+	for _, account := range accounts {
+		value := key(account)
+		if value == "" {
+			value = "(none)"
+		}
+		counts[value]++
+	}
+
+	result := make([]StatCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, StatCount{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+// writeStatsTable writes stats as a table with one row per field value
+func writeStatsTable(w io.Writer, stats AccountStats) error {
+	table := tablewriter.NewTable(w)
+	table.Header("Field", "Value", "Count")
+
+	groups := []struct {
+		field  string
+		counts []StatCount
+	}{
+		{"status", stats.Status},
+		{"joined_method", stats.JoinedMethod},
+		{"email_domain", stats.EmailDomain},
+	}
+	if err := table.Append([]string{"total", "", strconv.Itoa(stats.Total)}); err != nil {
+		return fmt.Errorf("failed to append table row: %w", err)
+	}
+	for _, group := range groups {
+		for _, count := range group.counts {
+			if err := table.Append([]string{group.field, count.Value, strconv.Itoa(count.Count)}); err != nil {
+				return fmt.Errorf("failed to append table row: %w", err)
+			}
+		}
+	}
+
+	return table.Render()
+}
+
+// writeStatsJSON writes stats as an indented JSON object
+func writeStatsJSON(w io.Writer, stats AccountStats) error {
+	jsonData, err := json.MarshalIndent(stats, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to create JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+// accountInfoReport is the result of checking an account info file
+type accountInfoReport struct {
+	problems []string
+	// accounts holds the parsed rows, with surrounding whitespace trimmed
+	accounts      []awsid.AccountInfo
+	untrimmedRows int
+	legacyRows    int
+}
+
+// validateAccountInfoFile checks every row of an account info CSV and
+// reports problems by line number. Unlike awsid.ParseAccountInfo it accepts rows
+// with a different number of columns so they can be reported.
+func validateAccountInfoFile(r io.Reader) (accountInfoReport, error) {
+	var report accountInfoReport
+
+	csvReader := csv.NewReader(awsid.SkipBOM(r))
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = -1
+
+	var validStatuses []string
+	for _, status := range types.AccountStatus("").Values() {
+		validStatuses = append(validStatuses, string(status))
+	}
+
+	optionalColumns := make(map[string]int)
+	expectedColumns := 0
+	linesByID := make(map[string][]int)
+	var ids []string
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		line, _ := csvReader.FieldPos(0)
+
+		// The first row sets the expected column count, whether it is a header or data
+		if expectedColumns == 0 {
+			expectedColumns = len(record)
+			if awsid.IsHeaderRow(record) {
+				optionalColumns = awsid.HeaderOptionalColumns(record)
+				continue
+			}
+		}
+
+		if len(record) != expectedColumns {
+			report.problems = append(report.problems, fmt.Sprintf("line %d: %d columns, expected %d", line, len(record), expectedColumns))
+		}
+		for _, value := range record {
+			if value != strings.TrimSpace(value) {
+				report.problems = append(report.problems, fmt.Sprintf("line %d: leading or trailing whitespace in a field", line))
+				report.untrimmedRows++
+				break
+			}
+		}
+
+		account, ok := awsid.ParseAccountRecord(record, optionalColumns)
+		if !ok {
+			report.problems = append(report.problems, fmt.Sprintf("line %d: no account ID, the row is ignored", line))
+			continue
+		}
+		report.accounts = append(report.accounts, account)
+
+		if _, ok := linesByID[account.ID]; !ok {
+			ids = append(ids, account.ID)
+		}
+		linesByID[account.ID] = append(linesByID[account.ID], line)
+
+		if !isValidAccountID(account.ID) {
+			report.problems = append(report.problems, fmt.Sprintf("line %d (%s): invalid account ID %q, must be 12 digits", line, awsid.AccountName(account), account.ID))
+		}
+
+		if account.Legacy() {
+			report.problems = append(report.problems, fmt.Sprintf("line %d (%s): old two-column row, run awsid migrate to fill in the missing fields", line, awsid.AccountName(account)))
+			report.legacyRows++
+			continue
+		}
+
+		var missing []string
+		for _, field := range awsid.AccountFields {
+			if account.FieldValue(field) == "" {
+				missing = append(missing, field)
+			}
+		}
+		if len(missing) > 0 {
+			report.problems = append(report.problems, fmt.Sprintf("line %d (%s): missing fields: %s", line, awsid.AccountName(account), strings.Join(missing, ", ")))
+		}
+
+		if account.Status != "" {
+			known := false
+			for _, status := range validStatuses {
+				if account.Status == status {
+					known = true
+					break
+				}
+			}
+			if !known {
+				report.problems = append(report.problems, fmt.Sprintf("line %d (%s): unknown status %q, expected one of %s", line, awsid.AccountName(account), account.Status, strings.Join(validStatuses, ", ")))
+			}
+		}
+
+		if account.Email != "" {
+			if address, err := mail.ParseAddress(account.Email); err != nil || address.Address != account.Email {
+				report.problems = append(report.problems, fmt.Sprintf("line %d (%s): invalid email address %q", line, awsid.AccountName(account), account.Email))
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if lines := linesByID[id]; len(lines) > 1 {
+			lineNumbers := make([]string, len(lines))
+			for i, line := range lines {
+				lineNumbers[i] = strconv.Itoa(line)
+			}
+			report.problems = append(report.problems, fmt.Sprintf("duplicate account ID %s on lines %s", id, strings.Join(lineNumbers, ", ")))
+		}
+	}
+	return report, nil
+}
+
+// isValidAccountID reports whether id is a 12-digit AWS account ID
+func isValidAccountID(id string) bool {
+	if len(id) != 12 {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateEnvVarName checks that name is usable as a shell variable name
+func validateEnvVarName(name string) error {
+	for i, r := range name {
+		if r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return fmt.Errorf("invalid --var-name \"%s\": use letters, digits and underscores, not starting with a digit", name)
+	}
+	if name == "" {
+		return fmt.Errorf("--var-name must not be empty")
+	}
+	return nil
+}
+
+// shellQuote quotes value for POSIX shells unless it only has safe characters
+func shellQuote(value string) string {
+	safe := value != ""
+	for _, r := range value {
+		if !(r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// accountAlias maps a short alias to the account name it stands for
+type accountAlias struct {
+	Name   string
+	Target string
+}
+
+// aliasesPath returns the path of the alias file, ~/.awsid-aliases
+func aliasesPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ".awsid-aliases"
+	}
+	return filepath.Join(homeDir, ".awsid-aliases")
+}
+
+// readAliases reads alias=account_name lines from path, skipping blank lines
+// and # comments. A missing file has no aliases.
+func readAliases(path string) ([]accountAlias, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alias file: %w", err)
+	}
+	defer file.Close()
+
+	var aliases []accountAlias
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, target, ok := strings.Cut(line, "=")
+		name, target = strings.TrimSpace(name), strings.TrimSpace(target)
+		if !ok || name == "" || target == "" {
+			return nil, fmt.Errorf("invalid alias on line %d of %s. Use alias=account_name", lineNumber, path)
+		}
+		aliases = append(aliases, accountAlias{Name: name, Target: target})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read alias file: %w", err)
+	}
+	return aliases, nil
+}
+
+// writeAliases replaces the alias file at path with aliases, one per line
+func writeAliases(path string, aliases []accountAlias) error {
+	var buf bytes.Buffer
+	for _, alias := range aliases {
+		fmt.Fprintf(&buf, "%s=%s\n", alias.Name, alias.Target)
+	}
+	// Replaced atomically like the cache, so an interrupted write keeps the old aliases
+	err := writeFileAtomically(path, 0600, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write alias file: %w", err)
+	}
+	return nil
+}
+
+// lookupAlias returns the account name an alias stands for. Later lines win
+// over earlier ones, as when the file is edited by hand.
+func lookupAlias(aliases []accountAlias, name string) (string, bool) {
+	target, found := "", false
+	for _, alias := range aliases {
+		if alias.Name == name {
+			target, found = alias.Target, true
+		}
+	}
+	return target, found
+}
+
+// selectAccountsWithAlias runs awsid.SelectAccounts with criteria.Name
+// expanded by aliases. When no account has the aliased name exactly, the term
+// itself is searched as usual. The criteria the results were selected with are
+// returned along with them.
+func selectAccountsWithAlias(accounts []awsid.AccountInfo, criteria awsid.Criteria, aliases []accountAlias) ([]awsid.AccountInfo, bool, awsid.Criteria) {
+	target, ok := lookupAlias(aliases, criteria.Name)
+	if criteria.Name == "" || !ok {
+		results, exact := awsid.SelectAccounts(accounts, criteria)
+		return results, exact, criteria
+	}
+
+	logger.Info("expanded alias", "alias", criteria.Name, "name", target)
+	expanded := criteria
+	expanded.Name = target
+	if results, exact := awsid.SelectAccounts(accounts, expanded); exact {
+		return results, exact, expanded
+	}
+
+	// No account has the aliased name, so search for the alias itself as usual
+	logger.Info("no account has the aliased name, searching the alias as a name", "name", target)
+	results, exact := awsid.SelectAccounts(accounts, criteria)
+	return results, exact, criteria
+}
+
+// validateAlias checks that an alias can be written to and read back from the
+// alias file and used as a search term
+func validateAlias(name, target string) error {
+	if name == "" || strings.ContainsAny(name, "=,# \t\r\n") {
+		return fmt.Errorf("invalid alias \"%s\". Aliases cannot be empty or contain spaces, =, # or commas", name)
+	}
+	if target == "" || strings.TrimSpace(target) != target || strings.ContainsAny(target, "\r\n") {
+		return fmt.Errorf("invalid account name \"%s\"", target)
+	}
+	return nil
+}
+
+// historyEntry is a line of the search history, ~/.awsid-history, in JSON Lines
+type historyEntry struct {
+	Timestamp string `json:"timestamp"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+}
+
+const (
+	// historyMaxEntries is the number of entries kept when the history is trimmed
+	historyMaxEntries = 1000
+	// historyTrimSize is the file size above which the history is trimmed
+	historyTrimSize = 256 * 1024
+)
+
+// historyPath returns the path of the search history, ~/.awsid-history
+func historyPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ".awsid-history"
+	}
+	return filepath.Join(homeDir, ".awsid-history")
+}
+
+// appendHistory records that account was resolved at now. Once the file grows
+// past historyTrimSize, only the newest historyMaxEntries entries are kept.
+func appendHistory(path string, account awsid.AccountInfo, now time.Time) error {
+	line, err := json.Marshal(historyEntry{Timestamp: now.Format(time.RFC3339), ID: account.ID, Name: account.Name})
+	if err != nil {
+		return err
+	}
+
+	// The history reveals which accounts were looked up, so keep it private like the cache
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() <= historyTrimSize {
+		return err
+	}
+	entries, err := readHistory(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > historyMaxEntries {
+		entries = entries[len(entries)-historyMaxEntries:]
+	}
+	return writeFileAtomically(path, 0600, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recordSearch appends the account a search resolved to to the history at
+// path. Only a single search mode resolving to exactly one account is recorded;
+// listings and ambiguous matches are not.
+func recordSearch(path string, searchModes int, results []awsid.AccountInfo, now time.Time) error {
+	if searchModes != 1 || len(results) != 1 {
+		return nil
+	}
+	return appendHistory(path, results[0], now)
+}
+
+// readHistory reads the search history, oldest first. A missing file is an
+// empty history, and lines that are not valid JSON, e.g. from an interrupted
+// write, are skipped.
+func readHistory(path string) ([]historyEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.Info("skipping invalid search history line", "path", path, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search history: %w", err)
+	}
+	return entries, nil
+}
+
+// writeHistory writes history entries newest first, at most limit of them
+// unless limit is 0, with timestamps in local time
+func writeHistory(w io.Writer, entries []historyEntry, limit int) error {
+	shown := 0
+	for i := len(entries) - 1; i >= 0 && (limit == 0 || shown < limit); i-- {
+		entry := entries[i]
+		timestamp := entry.Timestamp
+		if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+			timestamp = t.Local().Format("2006-01-02 15:04:05")
+		}
+		if _, err := fmt.Fprintf(w, "%s  %s  %s\n", timestamp, entry.ID, entry.Name); err != nil {
+			return err
+		}
+		shown++
+	}
+	return nil
+}
+
+// clearHistory deletes the search history at path. A missing history is already clear.
+func clearHistory(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear search history: %w", err)
+	}
+	return nil
+}
+
+// errCacheLocked is returned when another process holds the cache lock
+var errCacheLocked = errors.New("account info cache is locked by another awsid process")
+
+const (
+	// cacheLockTimeout is how long to wait for the cache lock before giving up
+	cacheLockTimeout = 3 * time.Second
+	// cacheLockRetryDelay is the interval between attempts to take the cache lock
+	cacheLockRetryDelay = 100 * time.Millisecond
+)
+
+// errInterrupted is returned when an update is canceled by SIGINT or SIGTERM
+var errInterrupted = errors.New("interrupted")
+
+// exitInterrupted is the conventional exit status after SIGINT (128 + 2)
+const exitInterrupted = 130
+
+// updateAccountInfoInterruptibly runs updateAccountInfoFromAWS with a context
+// canceled by SIGINT (Ctrl-C) or SIGTERM, returning errInterrupted in that case.
+// The cache file is only replaced after a complete fetch, so an interrupted
+// update leaves it untouched. Default signal handling is restored on return.
+func updateAccountInfoInterruptibly(filePath string, opts AWSOptions, fetchOpts FetchOptions) (updateResult, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := updateAccountInfoFromAWS(ctx, filePath, opts, fetchOpts)
+	if ctx.Err() != nil {
+		return updateResult{}, errInterrupted
+	}
+	return result, err
+}
+
+// fetchAccountsInterruptibly runs fetchAccountsFromAWS with a context canceled
+// by SIGINT or SIGTERM, returning errInterrupted in that case
+func fetchAccountsInterruptibly(opts AWSOptions, fetchOpts FetchOptions) ([]awsid.AccountInfo, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	accounts, err := fetchAccountsFromAWS(ctx, opts, fetchOpts)
+	if ctx.Err() != nil {
+		return nil, errInterrupted
+	}
+	return accounts, err
+}
+
+const (
+	// cacheFileMode keeps the cache, which holds account emails, private to its owner
+	cacheFileMode os.FileMode = 0600
+	// cacheDirMode is used for directories created for the cache, such as ~/.aws
+	cacheDirMode os.FileMode = 0700
+)
+
+// warnLoosePermissions warns when users other than the owner can access the
+// local cache at filePath
+func warnLoosePermissions(filePath string) {
+	if runtime.GOOS == "windows" {
+		return // Permission bits do not reflect Windows ACLs
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		warnf("Warning: %s is accessible by other users (mode %04o). Restrict it with: chmod 600 %s\n", filePath, mode, filePath)
+	}
+}
+
+// updateResult summarizes an update of the account info cache
+type updateResult struct {
+	Count int              // Number of accounts saved
+	Diff  *AccountInfoDiff // Changes from the previous cache; nil when there was none
+}
+
+// updateAccountInfoFromAWS fetches all accounts and saves them to filePath,
+// comparing them with the accounts previously saved there
+func updateAccountInfoFromAWS(ctx context.Context, filePath string, opts AWSOptions, fetchOpts FetchOptions) (updateResult, error) {
+	// Create the parent directory (~/.aws by default) if it doesn't exist
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, cacheDirMode); err != nil {
+		return updateResult{}, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	accounts, err := fetchAccountsFromAWS(ctx, opts, fetchOpts)
+	if err != nil {
+		return updateResult{}, err
+	}
+
+	// An unreadable previous cache only loses the comparison, not the update
+	result := updateResult{Count: len(accounts)}
+	if previous, err := awsid.ReadAccountInfo(filePath); err == nil {
+		diff := diffAccountInfo(previous, accounts)
+		result.Diff = &diff
+	} else if !os.IsNotExist(err) {
+		logger.Info("cannot read the previous cache for comparison", "path", filePath, "error", err)
+	}
+
+	// Save to CSV file
+	if err := saveAccountInfoToCSV(filePath, accounts); err != nil {
+		return updateResult{}, err
+	}
+	logger.Info("saved account info cache", "path", filePath)
+	return result, nil
+}
+
+// exitWebhookFailed is the exit status of update when the cache was updated
+// but the --webhook notification failed, so cron jobs can tell it apart
+const exitWebhookFailed = 2
+
+// webhookTimeout bounds the --webhook request
+const webhookTimeout = 10 * time.Second
+
+// webhookAccount is an account listed in a webhook notification
+type webhookAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// webhookPayload is the JSON body posted to --webhook. Slack incoming
+// webhooks display text; other receivers can use the structured fields.
+type webhookPayload struct {
+	Text    string           `json:"text"`
+	File    string           `json:"file"`
+	Added   []webhookAccount `json:"added"`
+	Removed []webhookAccount `json:"removed"`
+}
+
+// newWebhookPayload describes the accounts added and removed in the cache at filePath
+func newWebhookPayload(filePath string, diff AccountInfoDiff) webhookPayload {
+	payload := webhookPayload{File: filePath, Added: []webhookAccount{}, Removed: []webhookAccount{}}
+	lines := []string{fmt.Sprintf("awsid: accounts changed in %s: +%d new, -%d removed", filePath, len(diff.Added), len(diff.Removed))}
+	for _, account := range diff.Added {
+		payload.Added = append(payload.Added, webhookAccount{ID: account.ID, Name: awsid.AccountName(account)})
+		lines = append(lines, fmt.Sprintf("+ %s %s", account.ID, awsid.AccountName(account)))
+	}
+	for _, account := range diff.Removed {
+		payload.Removed = append(payload.Removed, webhookAccount{ID: account.ID, Name: awsid.AccountName(account)})
+		lines = append(lines, fmt.Sprintf("- %s %s", account.ID, awsid.AccountName(account)))
+	}
+	payload.Text = strings.Join(lines, "\n")
+	return payload
+}
+
+// validateWebhookURL checks that the --webhook value is an http or https URL
+func validateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("--webhook must be an http:// or https:// URL")
+	}
+	return nil
+}
+
+// postWebhook posts payload as JSON to webhookURL. Any status other than 2xx is an error.
+func postWebhook(webhookURL string, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// reportUpdateChanges prints the number of accounts added and removed by an
+// update to stderr, listing the added accounts when listNew is set. Nothing
+// is printed when the update added and removed no account.
+func reportUpdateChanges(diff *AccountInfoDiff, listNew bool) {
+	if diff == nil || (len(diff.Added) == 0 && len(diff.Removed) == 0) {
+		return
+	}
+	warnf("Accounts changed since the last update: +%d new, -%d removed\n", len(diff.Added), len(diff.Removed))
+	if listNew {
+		for _, account := range diff.Added {
+			warnf("  + %s %s\n", account.ID, awsid.AccountName(account))
+		}
+	}
+}
+
+// fetchAccountsFromAWS loads the AWS configuration and fetches all accounts
+// from AWS Organizations within opts.Timeout
+func fetchAccountsFromAWS(ctx context.Context, opts AWSOptions, fetchOpts FetchOptions) ([]awsid.AccountInfo, error) {
+	// Load AWS configuration
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	cfg, err := loadAWSConfig(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	// Resolve credentials up front so a missing or broken setup is reported as
+	// such rather than as a failed API call. They are cached for the calls below.
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out after %s: %w", opts.Timeout, err)
+		}
+		return nil, &credentialsError{err: err}
+	}
+
+	// Count API calls, including retries, and log their request IDs
+	apiCalls := 0
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("awsidLogAPICall", func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleDeserialize(ctx, in)
+			apiCalls++
+			requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+			logger.Debug("AWS API call", "operation", awsmiddleware.GetOperationName(ctx), "request_id", requestID, "error", err)
+			return out, metadata, err
+		}), middleware.After)
+	})
+
+	// Create Organizations client
+	client := organizations.NewFromConfig(cfg)
+
+	accounts, err := fetchAccounts(ctx, client, fetchOpts)
+	logger.Info("called AWS Organizations", "api_calls", apiCalls)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s: %w", opts.Timeout, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("fetched accounts from AWS Organizations", "accounts", len(accounts))
+	return accounts, nil
+}
+
+// credentialsError reports that no usable AWS credentials could be retrieved
+type credentialsError struct {
+	err error
+}
+
+func (e *credentialsError) Error() string {
+	return "failed to get AWS credentials: " + e.err.Error()
+}
+
+func (e *credentialsError) Unwrap() error {
+	return e.err
+}
+
+// awsErrorHint returns advice on fixing common AWS errors, such as missing
+// permissions or credentials, or "" when there is none for err
+func awsErrorHint(err error) string {
+	// Walk the chain to the API error, keeping the innermost operation, which
+	// is AssumeRole rather than ListAccounts when assuming --role-arn failed
+	var service, operation string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if opErr, ok := e.(*smithy.OperationError); ok {
+			service, operation = opErr.Service(), opErr.Operation()
+		}
+		apiErr, ok := e.(smithy.APIError)
+		if !ok {
+			continue
+		}
+		switch apiErr.ErrorCode() {
+		case "AccessDeniedException", "AccessDenied":
+			if operation == "" {
+				return "Hint: the AWS credentials are not allowed to call AWS. Check the IAM policy of the user or role"
+			}
+			return fmt.Sprintf("Hint: the AWS credentials need the %s:%s permission. Grant it to the IAM user or role, or use --profile or --role-arn for one that has it", strings.ToLower(service), operation)
+		case "AWSOrganizationsNotInUseException":
+			return "Hint: the account of the AWS credentials is not a member of an organization. Use credentials of the management account or a delegated administrator"
+		case "ExpiredToken", "ExpiredTokenException", "InvalidClientTokenId", "UnrecognizedClientException":
+			return "Hint: the AWS credentials are invalid or expired. Refresh them, e.g. with aws sso login, and try again"
+		}
+		return ""
+	}
+
+	var credErr *credentialsError
+	if errors.As(err, &credErr) {
+		return "Hint: no usable AWS credentials were found. Set them up with aws configure or aws sso login, or select them with --profile"
+	}
+	return ""
+}
+
+// s3URIPrefix marks --file values that refer to an object on S3
+const s3URIPrefix = "s3://"
+
+// isS3URI reports whether path is an s3://bucket/key URI
+func isS3URI(path string) bool {
+	return strings.HasPrefix(path, s3URIPrefix)
+}
+
+// parseS3URI splits an s3://bucket/key URI into bucket and key
+func parseS3URI(uri string) (string, string, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(uri, s3URIPrefix), "/")
+	if !ok || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URI \"%s\". Use s3://bucket/key", uri)
+	}
+	return bucket, key, nil
+}
+
+// newS3Client returns an S3 client for the region the bucket is in, which may
+// differ from the region used for Organizations
+func newS3Client(ctx context.Context, opts AWSOptions, bucket string) (*s3.Client, error) {
+	cfg, err := loadAWSConfig(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	// Emulators such as LocalStack behind a custom endpoint address buckets by path
+	pathStyle := cfg.BaseEndpoint != nil
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = pathStyle
+	})
+	region, err := manager.GetBucketRegion(ctx, client, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get region of bucket %s: %w", bucket, err)
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = pathStyle
+		o.Region = region
+		// Objects uploaded without a checksum are common; don't warn about them on stderr
+		o.DisableLogOutputChecksumValidationSkipped = true
+	}), nil
+}
+
+// readAccountInfoFromS3 downloads and parses the account info cache stored at an s3://bucket/key URI
+func readAccountInfoFromS3(uri string, opts AWSOptions) ([]awsid.AccountInfo, error) {
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	client, err := newS3Client(ctx, opts, bucket)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", uri, err)
+	}
+	defer output.Body.Close()
+
+	logger.Info("downloaded account info from S3", "uri", uri)
+	return awsid.ParseAccountInfo(output.Body)
+}
+
+// validateSyncS3 checks the --sync-s3 URI when given
+func validateSyncS3(uri string) error {
+	if uri == "" {
+		return nil
+	}
+	if !isS3URI(uri) {
+		return fmt.Errorf("--sync-s3 must be an s3://bucket/key URI")
+	}
+	_, _, err := parseS3URI(uri)
+	return err
+}
+
+// uploadAccountInfoToS3 uploads the local account info cache to an s3://bucket/key URI
+func uploadAccountInfoToS3(filePath string, uri string, opts AWSOptions) error {
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	client, err := newS3Client(ctx, opts, bucket)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        file,
+		ContentType: aws.String("text/csv"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", uri, err)
+	}
+
+	logger.Info("uploaded account info to S3", "uri", uri)
+	return nil
+}
+
+// OrganizationsAPI is the subset of the AWS Organizations client used by awsid.
+// It is satisfied by *organizations.Client and can be replaced by a fake in tests.
+type OrganizationsAPI interface {
+	ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error)
+	ListTagsForResource(ctx context.Context, params *organizations.ListTagsForResourceInput, optFns ...func(*organizations.Options)) (*organizations.ListTagsForResourceOutput, error)
+	ListParents(ctx context.Context, params *organizations.ListParentsInput, optFns ...func(*organizations.Options)) (*organizations.ListParentsOutput, error)
+	DescribeOrganizationalUnit(ctx context.Context, params *organizations.DescribeOrganizationalUnitInput, optFns ...func(*organizations.Options)) (*organizations.DescribeOrganizationalUnitOutput, error)
+}
+
+// fetchAccounts lists all accounts in the organization
+func fetchAccounts(ctx context.Context, client OrganizationsAPI, fetchOpts FetchOptions) ([]awsid.AccountInfo, error) {
+	// List accounts (follow NextToken until all pages are fetched)
+	var orgAccounts []types.Account
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		orgAccounts = append(orgAccounts, page.Accounts...)
+	}
+
+	// Prepare account info
+	var accounts []awsid.AccountInfo
+	ous := newOUResolver(client)
+	for _, account := range orgAccounts {
+		if account.Id != nil && account.Name != nil {
+			accountInfo := awsid.AccountInfo{
+				ID:   *account.Id,
+				Name: *account.Name,
+				// Backward compatibility
+				AliasName: *account.Name,
+				AccountID: *account.Id,
+			}
+
+			if account.Arn != nil {
+				accountInfo.Arn = *account.Arn
+			}
+			if account.Email != nil {
+				accountInfo.Email = *account.Email
+			}
+			accountInfo.Status = string(account.Status)
+			accountInfo.JoinedMethod = string(account.JoinedMethod)
+			if account.JoinedTimestamp != nil {
+				accountInfo.JoinedTimestamp = account.JoinedTimestamp.Format(timestampLayout)
+			}
+			// Tag and OU lookups are best effort: a failure for one account
+			// leaves those columns empty instead of aborting the whole update
+			if fetchOpts.WithTags {
+				tags, err := fetchTags(ctx, client, accountInfo.ID)
+				if err != nil {
+					if abortsFetch(ctx, err) {
+						return nil, err
 					}
+					warnf("Warning: %v\n", err)
+				} else {
+					accountInfo.Tags = tags
 				}
-
-				// Check for exact match first
-				exactMatch := []AccountInfo{}
-				for _, account := range matchingAccounts {
-					if account.AliasName == searchTerm {
-						exactMatch = append(exactMatch, account)
-						break
+			}
+			if fetchOpts.WithOU {
+				if err := fetchOU(ctx, ous, &accountInfo); err != nil {
+					if abortsFetch(ctx, err) {
+						return nil, err
 					}
+					warnf("Warning: %v\n", err)
 				}
+			}
 
-				// If exact match found
-				if len(exactMatch) > 0 {
-					sortAccounts(exactMatch, resolvedSort)
-					outputByFormat(exactMatch, resolvedFormat, true)
-					return
-				}
+			accounts = append(accounts, accountInfo)
+		}
+	}
 
-				// If partial matches found
-				if len(matchingAccounts) > 0 {
-					sortAccounts(matchingAccounts, resolvedSort)
-					outputByFormat(matchingAccounts, resolvedFormat, false)
-					return
-				}
+	return accounts, nil
+}
 
-				// No matches found
-				fmt.Fprintf(os.Stderr, "No account found with alias name: %s\n", searchTerm)
-				os.Exit(1)
-			} else {
-				// No search term provided, list all accounts
-				sortAccounts(accounts, resolvedSort)
-				outputByFormat(accounts, resolvedFormat, false)
+// abortsFetch reports whether a failed tag or OU lookup must stop the whole
+// fetch rather than leave one account incomplete: after a timeout or
+// interruption every later lookup fails too, and saving the partial result
+// would replace a complete cache
+func abortsFetch(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// fetchOU fills in the parent OU ID, name, path and ID path of an account. The account
+// is left unchanged if any lookup fails.
+func fetchOU(ctx context.Context, ous *ouResolver, account *awsid.AccountInfo) error {
+	parentID, err := ous.parent(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	name, err := ous.name(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	path, err := ous.path(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	idPath, err := ous.idPath(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	account.OUID = parentID
+	account.OUName = name
+	account.OUPath = path
+	account.OUIDPath = idPath
+	return nil
+}
+
+// fetchTags lists the Organizations tags attached to an account
+func fetchTags(ctx context.Context, client OrganizationsAPI, accountID string) (map[string]string, error) {
+	tags := make(map[string]string)
+	paginator := organizations.NewListTagsForResourcePaginator(client, &organizations.ListTagsForResourceInput{
+		ResourceId: aws.String(accountID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for account %s: %w", accountID, err)
+		}
+		for _, tag := range page.Tags {
+			if tag.Key != nil && tag.Value != nil {
+				tags[*tag.Key] = *tag.Value
 			}
-		},
+		}
 	}
+	return tags, nil
+}
 
-	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
-	rootCmd.Flags().BoolVar(&tableOutput, "table", false, "Output in table format")
-	rootCmd.Flags().BoolVar(&csvOutput, "csv", false, "Output in CSV format")
-	rootCmd.Flags().StringVar(&formatOption, "format", "", "Output format (json, table, csv)")
-	rootCmd.Flags().StringVar(&nameSearch, "name", "", "Search by account name (takes priority over positional argument)")
-	rootCmd.Flags().StringVar(&sortField, "sort", "", "Sort by field (id, name, email, status, joined_timestamp, joined_method)")
-	rootCmd.Flags().StringVar(&sortDesc, "sort-desc", "", "Sort by field in descending order (id, name, email, status, joined_timestamp, joined_method)")
+// rootOUName is the display name of the organization root in OU paths
+const rootOUName = "Root"
 
+// ouResolver resolves parents, names and paths of organizational units,
+// caching lookups so each OU is only described once
+type ouResolver struct {
+	client  OrganizationsAPI
+	parents map[string]string // Child ID -> parent ID
+	names   map[string]string // OU ID -> name
+}
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+func newOUResolver(client OrganizationsAPI) *ouResolver {
+	return &ouResolver{
+		client:  client,
+		parents: make(map[string]string),
+		names:   make(map[string]string),
 	}
 }
 
-// resolveFormatFlags resolves format conflicts and determines final format
-func resolveFormatFlags(formatOption string, jsonOutput, tableOutput, csvOutput bool) (string, error) {
-	// Count active format flags
-	activeFlags := 0
-	if jsonOutput {
-		activeFlags++
+// parent returns the ID of the OU or root containing an account or OU
+func (r *ouResolver) parent(ctx context.Context, childID string) (string, error) {
+	if parentID, ok := r.parents[childID]; ok {
+		return parentID, nil
 	}
-	if tableOutput {
-		activeFlags++
+
+	output, err := r.client.ListParents(ctx, &organizations.ListParentsInput{ChildId: aws.String(childID)})
+	if err != nil {
+		return "", fmt.Errorf("failed to list parents of %s: %w", childID, err)
 	}
-	if csvOutput {
-		activeFlags++
+	if len(output.Parents) == 0 || output.Parents[0].Id == nil {
+		return "", fmt.Errorf("no parent found for %s", childID)
 	}
-	
-	// Check for multiple individual format flags
-	if activeFlags > 1 {
-		return "", fmt.Errorf("multiple output format flags specified. Use only one format option")
+
+	parentID := *output.Parents[0].Id
+	r.parents[childID] = parentID
+	return parentID, nil
+}
+
+// name returns the name of an OU, or rootOUName for the organization root
+func (r *ouResolver) name(ctx context.Context, ouID string) (string, error) {
+	if strings.HasPrefix(ouID, "r-") {
+		return rootOUName, nil
 	}
-	
-	// If --format is specified, validate and use it (takes priority)
-	if formatOption != "" {
-		if err := validateFormat(formatOption); err != nil {
-			return "", err
-		}
-		return formatOption, nil
+	if name, ok := r.names[ouID]; ok {
+		return name, nil
 	}
-	
-	// If individual format flag is specified, use it
-	if jsonOutput {
-		return "json", nil
+
+	output, err := r.client.DescribeOrganizationalUnit(ctx, &organizations.DescribeOrganizationalUnitInput{
+		OrganizationalUnitId: aws.String(ouID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe organizational unit %s: %w", ouID, err)
+	}
+
+	var name string
+	if output.OrganizationalUnit != nil && output.OrganizationalUnit.Name != nil {
+		name = *output.OrganizationalUnit.Name
 	}
-	if tableOutput {
-		return "table", nil
+	r.names[ouID] = name
+	return name, nil
+}
+
+// path returns the OU names from the root down to the OU, e.g. "Root/Workloads/Prod"
+func (r *ouResolver) path(ctx context.Context, ouID string) (string, error) {
+	name, err := r.name(ctx, ouID)
+	if err != nil {
+		return "", err
 	}
-	if csvOutput {
-		return "csv", nil
+	if strings.HasPrefix(ouID, "r-") {
+		return name, nil
 	}
-	
-	// Default format (no flags specified - backward compatible behavior)
-	return "default", nil
+
+	parentID, err := r.parent(ctx, ouID)
+	if err != nil {
+		return "", err
+	}
+	parentPath, err := r.path(ctx, parentID)
+	if err != nil {
+		return "", err
+	}
+	return parentPath + "/" + name, nil
 }
 
-// validateFormat validates the format string
-func validateFormat(format string) error {
-	if format == "" {
-		return fmt.Errorf("output format cannot be empty. Supported formats: json, table, csv")
+// idPath returns the OU IDs from the root down to the OU, e.g. "r-abcd/ou-abcd-11111111"
+func (r *ouResolver) idPath(ctx context.Context, ouID string) (string, error) {
+	if strings.HasPrefix(ouID, "r-") {
+		return ouID, nil
 	}
-	
-	validFormats := []string{"json", "table", "csv"}
-	for _, valid := range validFormats {
-		if format == valid {
-			return nil
-		}
+
+	parentID, err := r.parent(ctx, ouID)
+	if err != nil {
+		return "", err
 	}
-	return fmt.Errorf("invalid output format \"%s\". Supported formats: json, table, csv", format)
+	parentPath, err := r.idPath(ctx, parentID)
+	if err != nil {
+		return "", err
+	}
+	return parentPath + "/" + ouID, nil
 }
 
-// SortInfo holds sort configuration
-type SortInfo struct {
-	Field      string
-	Descending bool
+// saveAccountInfoToCSV writes accounts atomically with writeFileAtomically
+// while holding the cache lock
+func saveAccountInfoToCSV(filePath string, accounts []awsid.AccountInfo) error {
+	// Serialize writers, e.g. a cron update and an interactive run, with a lock
+	// file next to the cache
+	lockPath := filePath + ".lock"
+	lock := flock.New(lockPath)
+	ctx, cancel := context.WithTimeout(context.Background(), cacheLockTimeout)
+	defer cancel()
+	locked, err := lock.TryLockContext(ctx, cacheLockRetryDelay)
+	if !locked {
+		if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %s", errCacheLocked, lockPath)
+		}
+		return fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+	defer lock.Unlock()
+
+	return writeFileAtomically(filePath, cacheFileMode, func(w io.Writer) error {
+		return writeAccountInfoCSV(w, accounts)
+	})
 }
 
-// resolveSortFlags validates and resolves sort configuration
-func resolveSortFlags(sortField, sortDesc string) (*SortInfo, error) {
-	// Check for conflicting sort flags
-	if sortField != "" && sortDesc != "" {
-		return nil, fmt.Errorf("cannot specify both --sort and --sort-desc. Use only one sort option")
+// writeFileAtomically replaces filePath with the output of write. The data is
+// written to a randomly named temporary file in the same directory, set to
+// mode and then renamed over the target, so readers never see a partially
+// written file and a failed write leaves the old file in place.
+func writeFileAtomically(filePath string, mode os.FileMode, write func(io.Writer) error) error {
+	dir := filepath.Dir(filePath)
+	tmpFile, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in %s: %w", dir, err)
 	}
-	
-	// No sort specified
-	if sortField == "" && sortDesc == "" {
-		return &SortInfo{}, nil
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if err := write(tmpFile); err != nil {
+		tmpFile.Close()
+		return err
 	}
-	
-	// Determine field and direction
-	var field string
-	var desc bool
-	
-	if sortField != "" {
-		field = sortField
-		desc = false
-	} else {
-		field = sortDesc
-		desc = true
+	if err := tmpFile.Chmod(mode); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to set permissions on %s: %w", tmpPath, err)
 	}
-	
-	// Validate sort field
-	if err := validateSortField(field); err != nil {
-		return nil, err
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file %s: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filePath, err)
 	}
-	
-	return &SortInfo{Field: field, Descending: desc}, nil
+	return nil
 }
 
-// validateSortField validates the sort field name
-func validateSortField(field string) error {
-	validFields := []string{"id", "name", "email", "status", "joined_timestamp", "joined_method"}
-	for _, valid := range validFields {
-		if field == valid {
-			return nil
+// writeAccountInfoCSV writes accounts in the account_info CSV format
+func writeAccountInfoCSV(w io.Writer, accounts []awsid.AccountInfo) error {
+	writer := csv.NewWriter(w)
+	columns := accountInfoColumns(accounts)
+
+	// Write header
+	if err := writer.Write(columns); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	// Write data
+	for _, account := range accounts {
+		row := make([]string, len(columns))
+		for i, field := range columns {
+			row[i] = account.FieldValue(field)
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV data: %w", err)
 		}
 	}
-	return fmt.Errorf("invalid sort field \"%s\". Supported fields: id, name, email, status, joined_timestamp, joined_method", field)
-}
-
-// sortAccounts sorts accounts based on the provided sort configuration
-func sortAccounts(accounts []AccountInfo, sortInfo *SortInfo) {
-	if sortInfo.Field == "" {
-		return // No sorting required
-	}
-	
-	sort.Slice(accounts, func(i, j int) bool {
-		var result bool
-		
-		switch sortInfo.Field {
-		case "id":
-			result = accounts[i].ID < accounts[j].ID
-		case "name":
-			result = strings.ToLower(accounts[i].Name) < strings.ToLower(accounts[j].Name)
-		case "email":
-			result = strings.ToLower(accounts[i].Email) < strings.ToLower(accounts[j].Email)
-		case "status":
-			result = accounts[i].Status < accounts[j].Status
-		case "joined_timestamp":
-			result = accounts[i].JoinedTimestamp < accounts[j].JoinedTimestamp
-		case "joined_method":
-			result = accounts[i].JoinedMethod < accounts[j].JoinedMethod
-		default:
-			return false // Should not happen due to validation
-		}
-		
-		// Reverse for descending order
-		if sortInfo.Descending {
-			result = !result
-		}
-		
-		return result
-	})
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV data: %w", err)
+	}
+	return nil
 }
 
-// outputByFormat outputs accounts using the specified format
-func outputByFormat(accounts []AccountInfo, format string, isExactMatch bool) {
-	switch format {
-	case "json":
-		outputJSON(accounts)
-	case "table":
-		outputTable(accounts)
-	case "csv":
-		outputCSV(accounts)
-	case "default":
-		// Default format: show account IDs for exact matches, detailed info for partial matches
-		if isExactMatch && len(accounts) > 0 {
-			fmt.Println(accounts[0].AccountID)
-		} else {
-			for _, account := range accounts {
-				fmt.Printf("ID: %s | ARN: %s | Email: %s | Name: %s | Status: %s | Method: %s | Joined: %s\n", 
-					account.ID, account.Arn, account.Email, account.Name, account.Status, account.JoinedMethod, account.JoinedTimestamp)
+// accountInfoColumns returns the columns stored for accounts: the account
+// fields, followed by the optional columns some account has a value for
+func accountInfoColumns(accounts []awsid.AccountInfo) []string {
+	columns := append([]string{}, awsid.AccountFields...)
+	for _, field := range awsid.OptionalFields {
+		for _, account := range accounts {
+			if account.FieldValue(field) != "" {
+				columns = append(columns, field)
+				break
 			}
 		}
-	default:
-		// Fallback to table format
-		outputTable(accounts)
 	}
+	return columns
 }
 
-func readAccountInfo(filePath string) ([]AccountInfo, error) {
-	// Open the file
+// sqliteHeader starts every SQLite database file
+const sqliteHeader = "SQLite format 3\x00"
+
+// isSQLiteFile reports whether filePath is a SQLite database rather than CSV
+func isSQLiteFile(filePath string) bool {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return false
 	}
 	defer file.Close()
 
-	accounts := []AccountInfo{}
-
-	// Read as CSV
-	csvReader := csv.NewReader(file)
-	csvReader.Comment = '#'
-	csvReader.TrimLeadingSpace = true
-	
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
 	}
-	
-	// Process CSV records
-	for i, record := range records {
-		// Skip header row if it looks like a header
-		if i == 0 && (len(record) > 0 && (record[0] == "alias_name" || record[0] == "AliasName" || record[0] == "id")) {
-			continue
-		}
-		
-		if len(record) >= 2 && record[0] != "" {
-			var account AccountInfo
-			
-			// Check if this is the new format (7 columns) or old format (2 columns)
-			if len(record) >= 7 {
-				// New format: id, arn, email, name, status, joined_method, joined_timestamp
-				account = AccountInfo{
-					ID:            strings.TrimSpace(record[0]),
-					Arn:           strings.TrimSpace(record[1]),
-					Email:         strings.TrimSpace(record[2]),
-					Name:          strings.TrimSpace(record[3]),
-					Status:        strings.TrimSpace(record[4]),
-					JoinedMethod:  strings.TrimSpace(record[5]),
-					JoinedTimestamp: strings.TrimSpace(record[6]),
-					// Backward compatibility
-					AliasName:     strings.TrimSpace(record[3]), // Name -> AliasName
-					AccountID:     strings.TrimSpace(record[0]), // ID -> AccountID
-				}
-			} else if len(record) >= 2 {
-				// Old format: alias_name, account_id
-				account = AccountInfo{
-					ID:            strings.TrimSpace(record[1]), // account_id -> ID
-					Name:          strings.TrimSpace(record[0]), // alias_name -> Name
-					AliasName:     strings.TrimSpace(record[0]),
-					AccountID:     strings.TrimSpace(record[1]),
-				}
-			}
-			
-			if account.ID != "" {
-				accounts = append(accounts, account)
-			}
-		}
-	}
-	
-	return accounts, nil
+	return string(header) == sqliteHeader
 }
 
+// readAccountInfoFromSQLite reads accounts from the accounts table of a SQLite
+// database written by --format sqlite. The table may have its columns in any
+// order; optional columns may be missing.
+func readAccountInfoFromSQLite(filePath string) ([]awsid.AccountInfo, error) {
+	db, err := sql.Open("sqlite", "file:"+filePath+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", filePath, err)
+	}
+	defer db.Close()
 
-func outputJSON(accounts []AccountInfo) {
-	output := AccountInfoList{
-		Accounts: accounts,
+	rows, err := db.Query("SELECT * FROM accounts")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the accounts table of %s: %w", filePath, err)
 	}
+	defer rows.Close()
 
-	jsonData, err := json.MarshalIndent(output, "", "    ")
+	tableColumns, err := rows.Columns()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating JSON: %v\n", err)
-		os.Exit(1)
+		return nil, err
+	}
+	positions := make(map[string]int, len(tableColumns))
+	for i, column := range tableColumns {
+		positions[column] = i
+	}
+
+	// Reorder each row into the account_info CSV layout to parse it the same way
+	var columns []string
+	for _, field := range awsid.AccountFields {
+		if _, ok := positions[field]; !ok {
+			return nil, fmt.Errorf("the accounts table of %s has no %s column", filePath, field)
+		}
+		columns = append(columns, field)
+	}
+	for _, field := range awsid.OptionalFields {
+		if _, ok := positions[field]; ok {
+			columns = append(columns, field)
+		}
 	}
+	optionalColumns := awsid.HeaderOptionalColumns(columns)
 
-	fmt.Println(string(jsonData))
+	accounts := []awsid.AccountInfo{}
+	values := make([]sql.NullString, len(tableColumns))
+	dest := make([]interface{}, len(tableColumns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to read the accounts table of %s: %w", filePath, err)
+		}
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = values[positions[column]].String
+		}
+		if account, ok := awsid.ParseAccountRecord(record, optionalColumns); ok {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, rows.Err()
 }
 
-func outputTable(accounts []AccountInfo) {
-	table := tablewriter.NewTable(os.Stdout)
-	table.Header("ID", "ARN", "Email", "Name", "Status", "Joined Method", "Joined Timestamp")
+// outputSQLite writes accounts as a SQLite database with an accounts table.
+// The database is built in a temporary file, since SQLite cannot write to a
+// stream, and then copied to the writer.
+func (m *DefaultOutputManager) outputSQLite(accounts []awsid.AccountInfo) error {
+	tmpFile, err := os.CreateTemp("", "awsid-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary database: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
 
-	for _, account := range accounts {
-		err := table.Append([]any{
-			account.ID, 
-			account.Arn, 
-			account.Email, 
-			account.Name, 
-			account.Status, 
-			account.JoinedMethod, 
-			account.JoinedTimestamp,
-		})
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error appending table row: %v\n", err)
-			continue
-		}
+	if err := writeAccountInfoSQLite(tmpPath, accounts); err != nil {
+		return err
 	}
 
-	table.Render()
+	database, err := os.Open(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	_, err = io.Copy(m.Writer, database)
+	return err
 }
-func outputCSV(accounts []AccountInfo) {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
 
-	// Write header
-	if err := writer.Write([]string{"id", "arn", "email", "name", "status", "joined_method", "joined_timestamp"}); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing CSV header: %v\n", err)
-		return
+// writeAccountInfoSQLite creates the accounts table in the SQLite database at
+// filePath and inserts accounts, with the same columns as the account_info CSV
+func writeAccountInfoSQLite(filePath string, accounts []awsid.AccountInfo) error {
+	db, err := sql.Open("sqlite", filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open SQLite database: %w", err)
 	}
+	defer db.Close()
 
-	// Write data
+	// Column names are known field names, so they can be put in the statements as is
+	columns := accountInfoColumns(accounts)
+	definitions := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		definitions[i] = column + " TEXT"
+		placeholders[i] = "?"
+	}
+	definitions[0] = "id TEXT PRIMARY KEY"
+	if _, err := db.Exec("CREATE TABLE accounts (" + strings.Join(definitions, ", ") + ")"); err != nil {
+		return fmt.Errorf("failed to create the accounts table: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op after Commit
+	insert, err := tx.Prepare("INSERT INTO accounts (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")")
+	if err != nil {
+		return fmt.Errorf("failed to prepare the insert: %w", err)
+	}
+	defer insert.Close()
 	for _, account := range accounts {
-		if err := writer.Write([]string{
-			account.ID, 
-			account.Arn, 
-			account.Email, 
-			account.Name, 
-			account.Status, 
-			account.JoinedMethod, 
-			account.JoinedTimestamp,
-		}); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing CSV row: %v\n", err)
-			continue
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = account.FieldValue(column)
+		}
+		if _, err := insert.Exec(values...); err != nil {
+			return fmt.Errorf("failed to insert account %s: %w", account.ID, err)
 		}
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to write the accounts table: %w", err)
+	}
+	return db.Close()
 }
 
-func updateAccountInfoFromAWS(filePath string) error {
-	// Create .aws directory if it doesn't exist
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
-	}
+// accountServer serves account searches from an in-memory copy of
+// the account info cache, replaced on every successful reload
+type accountServer struct {
+	load func() ([]awsid.AccountInfo, error)
 
-	// Load AWS configuration with us-east-1 region (Organizations is global but requires a region)
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion("us-east-1"))
-	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+	mu       sync.RWMutex
+	accounts []awsid.AccountInfo
+	loadedAt time.Time // Time of the last successful load
+	loadErr  error     // Error of the last load, nil when it succeeded
+
+	registry      *prometheus.Registry
+	accountsGauge *prometheus.GaugeVec
+	loadedAtGauge prometheus.Gauge
+}
+
+// newAccountServer returns a server reading the cache with load. Nothing is
+// served until the first reload succeeds.
+func newAccountServer(load func() ([]awsid.AccountInfo, error)) *accountServer {
+	s := &accountServer{
+		load:     load,
+		registry: prometheus.NewRegistry(),
+		accountsGauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "awsid_accounts_total",
+			Help: "Number of accounts in the served account info cache by status.",
+		}, []string{"status"}),
+		loadedAtGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "awsid_cache_last_loaded_timestamp_seconds",
+			Help: "Unix time of the last successful load of the account info cache.",
+		}),
 	}
+	s.registry.MustRegister(
+		s.accountsGauge,
+		s.loadedAtGauge,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return s
+}
 
-	// Create Organizations client
-	client := organizations.NewFromConfig(cfg)
+// reload reads the cache with load. On failure the previous accounts are kept.
+func (s *accountServer) reload() error {
+	accounts, err := s.load()
 
-	// List accounts
-	ctx := context.TODO()
-	result, err := client.ListAccounts(ctx, &organizations.ListAccountsInput{})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadErr = err
 	if err != nil {
-		return fmt.Errorf("failed to list accounts: %w", err)
+		return err
 	}
+	s.accounts = accounts
+	s.loadedAt = time.Now()
 
-	// Prepare account info
-	var accounts []AccountInfo
-	for _, account := range result.Accounts {
-		if account.Id != nil && account.Name != nil {
-			accountInfo := AccountInfo{
-				ID:     *account.Id,
-				Name:   *account.Name,
-				// Backward compatibility
-				AliasName: *account.Name,
-				AccountID: *account.Id,
-			}
-			
-			if account.Arn != nil {
-				accountInfo.Arn = *account.Arn
-			}
-			if account.Email != nil {
-				accountInfo.Email = *account.Email
-			}
-			accountInfo.Status = string(account.Status)
-			accountInfo.JoinedMethod = string(account.JoinedMethod)
-			if account.JoinedTimestamp != nil {
-				accountInfo.JoinedTimestamp = account.JoinedTimestamp.Format("2006-01-02T15:04:05.000000-07:00")
+	s.accountsGauge.Reset()
+	for _, count := range countAccountsBy(accounts, func(account awsid.AccountInfo) string { return account.Status }) {
+		s.accountsGauge.WithLabelValues(count.Value).Set(float64(count.Count))
+	}
+	s.loadedAtGauge.Set(float64(s.loadedAt.Unix()))
+	logger.Info("loaded accounts to serve", "accounts", len(accounts))
+	return nil
+}
+
+// reloadEvery reloads the cache at every interval until ctx is done
+func (s *accountServer) reloadEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reload(); err != nil {
+				warnf("Warning: Failed to reload account info: %v. Serving the previous accounts\n", err)
 			}
-			
-			accounts = append(accounts, accountInfo)
 		}
 	}
+}
 
-	// Save to CSV file
-	return saveAccountInfoToCSV(filePath, accounts)
+// handler returns the HTTP routes of the server
+func (s *accountServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /accounts", s.handleAccounts)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.Handle("GET /metrics", promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{}))
+	return mux
 }
 
-func saveAccountInfoToCSV(filePath string, accounts []AccountInfo) error {
-	file, err := os.Create(filePath)
+// serverHealth is the /healthz response body
+type serverHealth struct {
+	Status   string `json:"status"`
+	Accounts int    `json:"accounts"`
+	LoadedAt string `json:"loaded_at"`
+	Error    string `json:"error,omitempty"`
+}
+
+// handleHealthz reports whether the last load of the cache succeeded, with
+// 503 Service Unavailable when it failed
+func (s *accountServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	health := serverHealth{Status: "ok", Accounts: len(s.accounts), LoadedAt: s.loadedAt.Format(time.RFC3339)}
+	if s.loadErr != nil {
+		health.Status = "error"
+		health.Error = s.loadErr.Error()
+	}
+	s.mu.RUnlock()
+
+	body, err := json.Marshal(health)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filePath, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	defer file.Close()
+	w.Header().Set("Content-Type", "application/json")
+	if health.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(append(body, '\n'))
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// formatContentTypes maps output formats to their Content-Type; other formats are plain text
+var formatContentTypes = map[string]string{
+	"json":       "application/json",
+	"json-array": "application/json",
+	"map":        "application/json",
+	"jsonl":      "application/jsonl",
+	"yaml":       "application/yaml",
+	"csv":        "text/csv; charset=utf-8",
+	"tsv":        "text/tab-separated-values; charset=utf-8",
+	"markdown":   "text/markdown; charset=utf-8",
+	"sqlite":     "application/vnd.sqlite3",
+	"xlsx":       "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
 
-	// Write header
-	if err := writer.Write([]string{"id", "arn", "email", "name", "status", "joined_method", "joined_timestamp"}); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
+// handleAccounts searches, filters, sorts and outputs the accounts like the
+// CLI, taking the flag values as query parameters. The format defaults to
+// json, and no match is an empty result rather than an error.
+func (s *accountServer) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	badRequest := func(err error) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 	}
 
-	// Write data
-	for _, account := range accounts {
-		if err := writer.Write([]string{
-			account.ID, 
-			account.Arn, 
-			account.Email, 
-			account.Name, 
-			account.Status, 
-			account.JoinedMethod, 
-			account.JoinedTimestamp,
-		}); err != nil {
-			return fmt.Errorf("failed to write CSV data: %w", err)
+	format := params.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if err := validateFormat(format); err != nil {
+		badRequest(err)
+		return
+	}
+	roleName := params.Get("role-name")
+	if roleName == "" {
+		roleName = params.Get("role")
+	}
+	if format == "aws-config" && roleName == "" {
+		badRequest(fmt.Errorf("format aws-config requires role-name"))
+		return
+	}
+	fields, err := resolveFieldsFlag(params.Get("fields"))
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	statuses, err := resolveStatusFlag(params.Get("status"))
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	tagFilter, err := resolveTagFlags(params["tag"])
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	naturalSort := false
+	if value := params.Get("natural-sort"); value != "" {
+		if naturalSort, err = strconv.ParseBool(value); err != nil {
+			badRequest(fmt.Errorf("invalid natural-sort \"%s\". Use true or false", value))
+			return
+		}
+	}
+	sortInfo, err := resolveSortFlags(params.Get("sort"), params.Get("sort-desc"), naturalSort)
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	limit := 0
+	if value := params.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			badRequest(fmt.Errorf("invalid limit \"%s\". Use a non-negative integer", value))
+			return
+		}
+	}
+	ignoreCase := false
+	if value := params.Get("ignore-case"); value != "" {
+		if ignoreCase, err = strconv.ParseBool(value); err != nil {
+			badRequest(fmt.Errorf("invalid ignore-case \"%s\". Use true or false", value))
+			return
+		}
+	}
+	ouRecursive := false
+	if value := params.Get("ou-recursive"); value != "" {
+		if ouRecursive, err = strconv.ParseBool(value); err != nil {
+			badRequest(fmt.Errorf("invalid ou-recursive \"%s\". Use true or false", value))
+			return
 		}
 	}
+	since, until, err := resolveJoinedRange(params.Get("since"), params.Get("until"))
+	if err != nil {
+		badRequest(err)
+		return
+	}
 
-	return nil
-}
\ No newline at end of file
+	query := awsid.Query{
+		ID:         params.Get("id"),
+		All:        params.Get("search-all"),
+		Prefix:     params.Get("prefix"),
+		Suffix:     params.Get("suffix"),
+		Glob:       params.Get("glob"),
+		IgnoreCase: ignoreCase,
+	}
+	if query.Glob != "" {
+		if err := validateGlobPattern(query.Glob); err != nil {
+			badRequest(err)
+			return
+		}
+	}
+	if pattern := params.Get("regex"); pattern != "" {
+		if query.Regex, err = compileSearchRegex(pattern, ignoreCase); err != nil {
+			badRequest(err)
+			return
+		}
+	}
+	if names := splitCommaSeparated(params.Get("name")); len(names) > 1 {
+		query.Names = names
+	} else if len(names) == 1 {
+		query.Name = names[0]
+	}
+
+	s.mu.RLock()
+	accounts := s.accounts
+	s.mu.RUnlock()
+
+	results, _ := awsid.SelectAccounts(accounts, awsid.Criteria{
+		Filter: awsid.Filter{
+			Statuses:     statuses,
+			EmailDomains: splitCommaSeparated(params.Get("email-domain")),
+			Tags:         tagFilter,
+			OU:           params.Get("ou"),
+			OURecursive:  ouRecursive,
+			JoinedSince:  since,
+			JoinedUntil:  until,
+		},
+		Query: query,
+	})
+
+	// Sorting works in place, so sort a copy to leave the served accounts untouched
+	results = append([]awsid.AccountInfo{}, results...)
+	awsid.SortAccounts(results, sortInfo.Keys)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	// Render into a buffer so a formatting error can still be reported with a status code
+	var buf bytes.Buffer
+	outputManager := NewOutputManager(&buf)
+	outputManager.Fields = fields
+	outputManager.RoleName = roleName
+	if err := outputManager.Output(results, format, false); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	contentType, ok := formatContentTypes[format]
+	if !ok {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
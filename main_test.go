@@ -0,0 +1,1376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/gofrs/flock"
+	"github.com/juliar13/awsid/pkg/awsid"
+	"github.com/spf13/cobra"
+	"github.com/xuri/excelize/v2"
+)
+
+// csvAgeDays writes accounts as CSV with the age_days column and returns the
+// age_days value of each row
+func csvAgeDays(t *testing.T, accounts []awsid.AccountInfo) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	m := NewOutputManager(&buf)
+	m.ExtraColumns = []string{"age_days"}
+	if err := m.Output(accounts, "csv", false); err != nil {
+		t.Fatalf("Output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var ages []string
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ",")
+		ages = append(ages, fields[len(fields)-1])
+	}
+	return ages
+}
+
+func TestAgeDaysSurvivesTimeFormat(t *testing.T) {
+	accounts := []awsid.AccountInfo{{ID: "111111111111", Name: "a", JoinedTimestamp: "2024-02-24T13:08:50.690000+09:00"}}
+	setAgeDays(accounts)
+	want := *accounts[0].AgeDays
+
+	formatTimestamps(accounts, true, "2006-01-02")
+	if got := accounts[0].FieldValue("age_days"); got != strconv.Itoa(want) {
+		t.Errorf("FieldValue(age_days) after formatTimestamps = %q, want %d", got, want)
+	}
+	if ages := csvAgeDays(t, accounts); ages[0] != strconv.Itoa(want) {
+		t.Errorf("CSV age_days = %q, want %d", ages[0], want)
+	}
+}
+
+func TestAgeDaysSurvivesRelativeTime(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	accounts := []awsid.AccountInfo{{ID: "111111111111", Name: "a", JoinedTimestamp: now.AddDate(0, 0, -3).Format(time.RFC3339)}}
+	setAgeDays(accounts)
+	want := *accounts[0].AgeDays
+
+	relativeTimestamps(accounts, now)
+	if accounts[0].JoinedTimestamp != "3 days ago" {
+		t.Fatalf("JoinedTimestamp = %q, want \"3 days ago\"", accounts[0].JoinedTimestamp)
+	}
+	if ages := csvAgeDays(t, accounts); ages[0] != strconv.Itoa(want) {
+		t.Errorf("CSV age_days = %q, want %d", ages[0], want)
+	}
+}
+
+// fakeOrganizations is an in-memory OrganizationsAPI serving accounts in pages
+type fakeOrganizations struct {
+	pages      [][]types.Account
+	tags       map[string][]types.Tag // Account ID -> tags
+	parents    map[string]string      // Child ID -> parent ID
+	ouNames    map[string]string      // OU ID -> name
+	listErr    error                  // Returned by ListAccounts
+	tagErrs    map[string]error       // Account ID -> ListTagsForResource error
+	parentErrs map[string]error       // Child ID -> ListParents error
+	onListTags func()                 // Called by ListTagsForResource, e.g. to cancel the context mid-fetch
+	listCalls  int
+	pageTokens []string // NextToken received by each ListAccounts call
+}
+
+func (f *fakeOrganizations) ListAccounts(ctx context.Context, params *organizations.ListAccountsInput, optFns ...func(*organizations.Options)) (*organizations.ListAccountsOutput, error) {
+	f.listCalls++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	page := 0
+	if params.NextToken != nil {
+		f.pageTokens = append(f.pageTokens, *params.NextToken)
+		page, _ = strconv.Atoi(*params.NextToken)
+	}
+	output := &organizations.ListAccountsOutput{}
+	if page < len(f.pages) {
+		output.Accounts = f.pages[page]
+	}
+	if page+1 < len(f.pages) {
+		output.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return output, nil
+}
+
+func (f *fakeOrganizations) ListTagsForResource(ctx context.Context, params *organizations.ListTagsForResourceInput, optFns ...func(*organizations.Options)) (*organizations.ListTagsForResourceOutput, error) {
+	if f.onListTags != nil {
+		f.onListTags()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.tagErrs[*params.ResourceId]; err != nil {
+		return nil, err
+	}
+	return &organizations.ListTagsForResourceOutput{Tags: f.tags[*params.ResourceId]}, nil
+}
+
+func (f *fakeOrganizations) ListParents(ctx context.Context, params *organizations.ListParentsInput, optFns ...func(*organizations.Options)) (*organizations.ListParentsOutput, error) {
+	if err := f.parentErrs[*params.ChildId]; err != nil {
+		return nil, err
+	}
+	parentID, ok := f.parents[*params.ChildId]
+	if !ok {
+		return &organizations.ListParentsOutput{}, nil
+	}
+	return &organizations.ListParentsOutput{Parents: []types.Parent{{Id: aws.String(parentID)}}}, nil
+}
+
+func (f *fakeOrganizations) DescribeOrganizationalUnit(ctx context.Context, params *organizations.DescribeOrganizationalUnitInput, optFns ...func(*organizations.Options)) (*organizations.DescribeOrganizationalUnitOutput, error) {
+	name, ok := f.ouNames[*params.OrganizationalUnitId]
+	if !ok {
+		return nil, errors.New("OrganizationalUnitNotFoundException")
+	}
+	return &organizations.DescribeOrganizationalUnitOutput{
+		OrganizationalUnit: &types.OrganizationalUnit{Id: params.OrganizationalUnitId, Name: aws.String(name)},
+	}, nil
+}
+
+// fakeAccount returns an active Organizations account with a derived name
+func fakeAccount(id string) types.Account {
+	return types.Account{
+		Id:     aws.String(id),
+		Name:   aws.String("account-" + id),
+		Email:  aws.String(id + "@example.com"),
+		Status: types.AccountStatusActive,
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe: %v", err)
+	}
+	orig := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = orig }()
+
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestFetchAccountsFollowsPages(t *testing.T) {
+	client := &fakeOrganizations{pages: [][]types.Account{
+		{fakeAccount("111111111111"), fakeAccount("222222222222")},
+		{fakeAccount("333333333333")},
+		{fakeAccount("444444444444")},
+	}}
+
+	accounts, err := fetchAccounts(context.Background(), client, FetchOptions{})
+	if err != nil {
+		t.Fatalf("fetchAccounts: %v", err)
+	}
+	if client.listCalls != 3 {
+		t.Errorf("ListAccounts called %d times, want 3", client.listCalls)
+	}
+	if want := []string{"1", "2"}; !slices.Equal(client.pageTokens, want) {
+		t.Errorf("NextToken sequence = %v, want %v", client.pageTokens, want)
+	}
+	var ids []string
+	for _, a := range accounts {
+		ids = append(ids, a.ID)
+	}
+	if want := []string{"111111111111", "222222222222", "333333333333", "444444444444"}; !slices.Equal(ids, want) {
+		t.Errorf("account IDs = %v, want %v", ids, want)
+	}
+	if accounts[0].Name != "account-111111111111" || accounts[0].Email != "111111111111@example.com" || accounts[0].Status != "ACTIVE" {
+		t.Errorf("unexpected account fields: %+v", accounts[0])
+	}
+}
+
+func TestFetchAccountsTagAndOUFailuresWarn(t *testing.T) {
+	client := &fakeOrganizations{
+		pages: [][]types.Account{{fakeAccount("111111111111"), fakeAccount("222222222222")}},
+		tags: map[string][]types.Tag{
+			"111111111111": {{Key: aws.String("Team"), Value: aws.String("platform")}},
+		},
+		parents: map[string]string{
+			"111111111111":     "ou-abcd-11111111",
+			"ou-abcd-11111111": "r-abcd",
+		},
+		ouNames:    map[string]string{"ou-abcd-11111111": "Production"},
+		tagErrs:    map[string]error{"222222222222": errors.New("AccessDeniedException")},
+		parentErrs: map[string]error{"222222222222": errors.New("AccessDeniedException")},
+	}
+
+	var accounts []awsid.AccountInfo
+	var err error
+	stderr := captureStderr(t, func() {
+		accounts, err = fetchAccounts(context.Background(), client, FetchOptions{WithTags: true, WithOU: true})
+	})
+	if err != nil {
+		t.Fatalf("fetchAccounts: %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("got %d accounts, want 2", len(accounts))
+	}
+
+	ok := accounts[0]
+	if ok.Tags["Team"] != "platform" || ok.OUName != "Production" || ok.OUPath != "Root/Production" || ok.OUIDPath != "r-abcd/ou-abcd-11111111" {
+		t.Errorf("account without failures lost data: tags=%v ou=%q path=%q id path=%q", ok.Tags, ok.OUName, ok.OUPath, ok.OUIDPath)
+	}
+	failed := accounts[1]
+	if failed.Tags != nil || failed.OUID != "" || failed.OUName != "" || failed.OUPath != "" || failed.OUIDPath != "" {
+		t.Errorf("failed lookups should leave tags and OU empty: %+v", failed)
+	}
+	for _, want := range []string{"failed to list tags for account 222222222222", "failed to list parents of 222222222222"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr %q does not contain %q", stderr, want)
+		}
+	}
+}
+
+func TestFetchAccountsStopsWhenCanceled(t *testing.T) {
+	newClient := func() *fakeOrganizations {
+		return &fakeOrganizations{
+			pages: [][]types.Account{{fakeAccount("111111111111"), fakeAccount("222222222222")}},
+			parents: map[string]string{
+				"111111111111": "r-abcd",
+				"222222222222": "r-abcd",
+			},
+		}
+	}
+
+	t.Run("canceled during tag lookup", func(t *testing.T) {
+		// As on Ctrl-C while tags are being fetched
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		client := newClient()
+		client.onListTags = cancel
+
+		var err error
+		stderr := captureStderr(t, func() {
+			_, err = fetchAccounts(ctx, client, FetchOptions{WithTags: true, WithOU: true})
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("fetchAccounts error = %v, want context.Canceled", err)
+		}
+		if stderr != "" {
+			t.Errorf("cancellation should not be reported as per-account warnings: %q", stderr)
+		}
+	})
+
+	t.Run("timed out OU lookup", func(t *testing.T) {
+		client := newClient()
+		client.parentErrs = map[string]error{"111111111111": fmt.Errorf("operation error: %w", context.DeadlineExceeded)}
+
+		_, err := fetchAccounts(context.Background(), client, FetchOptions{WithOU: true})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("fetchAccounts error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestFetchAccountsListFailure(t *testing.T) {
+	client := &fakeOrganizations{listErr: errors.New("AWSOrganizationsNotInUseException")}
+
+	accounts, err := fetchAccounts(context.Background(), client, FetchOptions{})
+	if err == nil {
+		t.Fatalf("fetchAccounts returned %d accounts, want an error", len(accounts))
+	}
+	if !strings.Contains(err.Error(), "failed to list accounts") || !strings.Contains(err.Error(), "AWSOrganizationsNotInUseException") {
+		t.Errorf("error = %q, want it to wrap the ListAccounts failure", err)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "123456789012", Name: "prod", Email: "prod@example.com", Status: "ACTIVE", JoinedMethod: "CREATED", JoinedTimestamp: "2024-02-24T13:08:50+09:00"},
+		{ID: "023456789013", Name: "dev", Status: "SUSPENDED"},
+	}
+
+	tests := []struct {
+		format   string
+		accounts []awsid.AccountInfo
+		want     string
+	}{
+		{"json", accounts, `{
+    "account_info": [
+        {
+            "id": "123456789012",
+            "arn": "",
+            "email": "prod@example.com",
+            "name": "prod",
+            "status": "ACTIVE",
+            "joined_method": "CREATED",
+            "joined_timestamp": "2024-02-24T13:08:50+09:00",
+            "alias_name": "",
+            "account_id": ""
+        },
+        {
+            "id": "023456789013",
+            "arn": "",
+            "email": "",
+            "name": "dev",
+            "status": "SUSPENDED",
+            "joined_method": "",
+            "joined_timestamp": "",
+            "alias_name": "",
+            "account_id": ""
+        }
+    ]
+}
+`},
+		{"json", nil, `{
+    "account_info": []
+}
+`},
+		{"jsonl", accounts, `{"id":"123456789012","arn":"","email":"prod@example.com","name":"prod","status":"ACTIVE","joined_method":"CREATED","joined_timestamp":"2024-02-24T13:08:50+09:00","alias_name":"","account_id":""}
+{"id":"023456789013","arn":"","email":"","name":"dev","status":"SUSPENDED","joined_method":"","joined_timestamp":"","alias_name":"","account_id":""}
+`},
+		{"jsonl", nil, ""},
+		{"csv", accounts, `id,arn,email,name,status,joined_method,joined_timestamp
+123456789012,,prod@example.com,prod,ACTIVE,CREATED,2024-02-24T13:08:50+09:00
+023456789013,,,dev,SUSPENDED,,
+`},
+		{"csv", nil, "id,arn,email,name,status,joined_method,joined_timestamp\n"},
+		{"tsv", accounts, "id\tarn\temail\tname\tstatus\tjoined_method\tjoined_timestamp\n" +
+			"123456789012\t\tprod@example.com\tprod\tACTIVE\tCREATED\t2024-02-24T13:08:50+09:00\n" +
+			"023456789013\t\t\tdev\tSUSPENDED\t\t\n"},
+		{"tsv", nil, "id\tarn\temail\tname\tstatus\tjoined_method\tjoined_timestamp\n"},
+		{"yaml", accounts, `account_info:
+    - id: "123456789012"
+      arn: ""
+      email: prod@example.com
+      name: prod
+      status: ACTIVE
+      joined_method: CREATED
+      joined_timestamp: "2024-02-24T13:08:50+09:00"
+      alias_name: ""
+      account_id: ""
+    - id: "023456789013"
+      arn: ""
+      email: ""
+      name: dev
+      status: SUSPENDED
+      joined_method: ""
+      joined_timestamp: ""
+      alias_name: ""
+      account_id: ""
+`},
+		{"yaml", nil, "account_info: []\n"},
+		{"markdown", accounts, `| ID | ARN | Email | Name | Status | Joined Method | Joined Timestamp |
+|---|---|---|---|---|---|---|
+| 123456789012 |  | prod@example.com | prod | ACTIVE | CREATED | 2024-02-24T13:08:50+09:00 |
+| 023456789013 |  |  | dev | SUSPENDED |  |  |
+`},
+		{"markdown", nil, `| ID | ARN | Email | Name | Status | Joined Method | Joined Timestamp |
+|---|---|---|---|---|---|---|
+`},
+		{"table", accounts, `┌──────────────┬─────┬──────────────────┬──────┬───────────┬───────────────┬───────────────────────────┐
+│      ID      │ ARN │      EMAIL       │ NAME │  STATUS   │ JOINED METHOD │     JOINED TIMESTAMP      │
+├──────────────┼─────┼──────────────────┼──────┼───────────┼───────────────┼───────────────────────────┤
+│ 123456789012 │     │ prod@example.com │ prod │ ACTIVE    │ CREATED       │ 2024-02-24T13:08:50+09:00 │
+│ 023456789013 │     │                  │ dev  │ SUSPENDED │               │                           │
+└──────────────┴─────┴──────────────────┴──────┴───────────┴───────────────┴───────────────────────────┘
+`},
+		{"table", nil, `┌────┬─────┬───────┬──────┬────────┬───────────────┬──────────────────┐
+│ ID │ ARN │ EMAIL │ NAME │ STATUS │ JOINED METHOD │ JOINED TIMESTAMP │
+└────┴─────┴───────┴──────┴────────┴───────────────┴──────────────────┘
+`},
+	}
+
+	for _, tt := range tests {
+		name := tt.format
+		if len(tt.accounts) == 0 {
+			name += "/empty"
+		}
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewOutputManager(&buf).Output(tt.accounts, tt.format, false); err != nil {
+				t.Fatalf("Output: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Output(%s) =\n%s\nwant:\n%s", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLargeOrganizationIsCachedCompletely(t *testing.T) {
+	// ListAccounts returns at most 20 accounts per page
+	const total, pageSize = 350, 20
+	client := &fakeOrganizations{}
+	var wantIDs []string
+	for i := 0; i < total; i++ {
+		id := fmt.Sprintf("%012d", 100000000000+i)
+		wantIDs = append(wantIDs, id)
+		if i%pageSize == 0 {
+			client.pages = append(client.pages, nil)
+		}
+		last := len(client.pages) - 1
+		client.pages[last] = append(client.pages[last], fakeAccount(id))
+	}
+
+	accounts, err := fetchAccounts(context.Background(), client, FetchOptions{})
+	if err != nil {
+		t.Fatalf("fetchAccounts: %v", err)
+	}
+	if want := (total + pageSize - 1) / pageSize; client.listCalls != want {
+		t.Errorf("ListAccounts called %d times, want %d", client.listCalls, want)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "account_info")
+	if err := saveAccountInfoToCSV(filePath, accounts); err != nil {
+		t.Fatalf("saveAccountInfoToCSV: %v", err)
+	}
+	cached, err := awsid.ReadAccountInfo(filePath)
+	if err != nil {
+		t.Fatalf("ReadAccountInfo: %v", err)
+	}
+
+	var gotIDs []string
+	for _, account := range cached {
+		gotIDs = append(gotIDs, account.ID)
+		if account.Name != "account-"+account.ID {
+			t.Errorf("account %s cached with name %q", account.ID, account.Name)
+		}
+	}
+	if !slices.Equal(gotIDs, wantIDs) {
+		t.Errorf("cached %d accounts, want all %d in order", len(gotIDs), total)
+	}
+}
+
+func TestOutputTSVEscapesTabsAndNewlines(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "111111111111", Name: "team\tprod", Status: "ACTIVE"},
+		{ID: "222222222222", Name: "line1\nline2", Status: "ACTIVE"},
+		{ID: "333333333333", Name: `say "hi"`, Status: "ACTIVE"},
+	}
+
+	var buf bytes.Buffer
+	m := NewOutputManager(&buf)
+	m.NoHeader = true
+	if err := m.Output(accounts, "tsv", false); err != nil {
+		t.Fatalf("Output: %v", err)
+	}
+
+	want := "111111111111\t\t\t\"team\tprod\"\tACTIVE\t\t\n" +
+		"222222222222\t\t\t\"line1\nline2\"\tACTIVE\t\t\n" +
+		"333333333333\t\t\t\"say \"\"hi\"\"\"\tACTIVE\t\t\n"
+	if got := buf.String(); got != want {
+		t.Errorf("TSV output =\n%q\nwant:\n%q", got, want)
+	}
+
+	// Quoted fields keep every row at seven columns for TSV readers
+	reader := csv.NewReader(&buf)
+	reader.Comma = '\t'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("reading TSV back: %v", err)
+	}
+	for i, record := range records {
+		if len(record) != 7 || record[3] != accounts[i].Name {
+			t.Errorf("row %d read back as %q, want name %q in 7 columns", i, record, accounts[i].Name)
+		}
+	}
+}
+
+func TestExactMatchWithDuplicateNames(t *testing.T) {
+	// Old two-column caches can hold the same alias for several accounts
+	accounts, err := awsid.ParseAccountInfo(strings.NewReader("shared,111111111111\nshared,222222222222\nshared-dev,333333333333\n"))
+	if err != nil {
+		t.Fatalf("ParseAccountInfo: %v", err)
+	}
+
+	results, exact := awsid.SearchAccounts(accounts, awsid.Query{Name: "shared"})
+	if !exact {
+		t.Errorf("exact = false, want true")
+	}
+	var ids []string
+	for _, account := range results {
+		ids = append(ids, account.ID)
+	}
+	if want := []string{"111111111111", "222222222222"}; !slices.Equal(ids, want) {
+		t.Fatalf("exact matches = %v, want %v", ids, want)
+	}
+
+	// The default format keeps printing a single ID for scripts
+	var buf bytes.Buffer
+	if err := NewOutputManager(&buf).Output(results, "default", exact); err != nil {
+		t.Fatalf("Output(default): %v", err)
+	}
+	if got := buf.String(); got != "111111111111\n" {
+		t.Errorf("default output = %q, want the first ID only", got)
+	}
+
+	// Other formats list every exact match
+	for _, format := range []string{"json", "csv"} {
+		buf.Reset()
+		if err := NewOutputManager(&buf).Output(results, format, exact); err != nil {
+			t.Fatalf("Output(%s): %v", format, err)
+		}
+		for _, id := range []string{"111111111111", "222222222222"} {
+			if !strings.Contains(buf.String(), id) {
+				t.Errorf("%s output does not contain %s:\n%s", format, id, buf.String())
+			}
+		}
+		if strings.Contains(buf.String(), "333333333333") {
+			t.Errorf("%s output contains the partial match 333333333333:\n%s", format, buf.String())
+		}
+	}
+}
+
+func TestAccountInfoCacheRoundTrip(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "123456789012", Name: "Prod, Tokyo", Email: "a@example.com", Status: "ACTIVE", JoinedMethod: "CREATED", JoinedTimestamp: "2024-02-24T13:08:50+09:00"},
+		{ID: "023456789013", Name: `The "main" account`, Email: "b@example.com", Status: "SUSPENDED", JoinedMethod: "INVITED"},
+		{ID: "223456789014", Name: "開発 \"dev\", 東京", Status: "ACTIVE"},
+	}
+	filePath := filepath.Join(t.TempDir(), "account_info")
+	if err := saveAccountInfoToCSV(filePath, accounts); err != nil {
+		t.Fatalf("saveAccountInfoToCSV: %v", err)
+	}
+
+	check := func(t *testing.T, got []awsid.AccountInfo) {
+		t.Helper()
+		if len(got) != len(accounts) {
+			t.Fatalf("read back %d accounts, want %d", len(got), len(accounts))
+		}
+		for i, want := range accounts {
+			if got[i].ID != want.ID || got[i].Name != want.Name || got[i].Email != want.Email ||
+				got[i].Status != want.Status || got[i].JoinedMethod != want.JoinedMethod || got[i].JoinedTimestamp != want.JoinedTimestamp {
+				t.Errorf("account %d read back as %+v, want %+v", i, got[i], want)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	t.Run("as saved", func(t *testing.T) {
+		got, err := awsid.ParseAccountInfo(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("ParseAccountInfo: %v", err)
+		}
+		check(t, got)
+	})
+	t.Run("with BOM", func(t *testing.T) {
+		// As re-saved by Excel
+		got, err := awsid.ParseAccountInfo(bytes.NewReader(append([]byte("\xEF\xBB\xBF"), data...)))
+		if err != nil {
+			t.Fatalf("ParseAccountInfo: %v", err)
+		}
+		check(t, got)
+	})
+}
+
+func TestHandleAccountsFilters(t *testing.T) {
+	server := newAccountServer(func() ([]awsid.AccountInfo, error) {
+		return []awsid.AccountInfo{
+			{ID: "111111111111", Name: "prod", AliasName: "prod", Status: "ACTIVE", JoinedTimestamp: "2023-01-15T10:00:00Z", OUName: "Production", OUPath: "Root/Workloads/Production"},
+			{ID: "222222222222", Name: "stg", AliasName: "stg", Status: "ACTIVE", JoinedTimestamp: "2024-06-01T10:00:00Z", OUName: "Staging", OUPath: "Root/Workloads/Staging"},
+			{ID: "333333333333", Name: "sandbox", AliasName: "sandbox", Status: "ACTIVE", JoinedTimestamp: "2025-03-01T10:00:00Z", OUName: "Sandbox", OUPath: "Root/Sandbox"},
+		}, nil
+	})
+	if err := server.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string // Exact body for format=ids, or a substring of other formats
+	}{
+		{"ou-recursive", "?format=ids&ou=Workloads&ou-recursive=true", http.StatusOK, "111111111111\n222222222222\n"},
+		{"ou without ou-recursive", "?format=ids&ou=Workloads", http.StatusOK, ""},
+		{"since", "?format=ids&since=2024-01-01", http.StatusOK, "222222222222\n333333333333\n"},
+		{"until", "?format=ids&until=2024-06-02", http.StatusOK, "111111111111\n222222222222\n"},
+		{"since and until", "?format=ids&since=2024-01-01&until=2024-12-31", http.StatusOK, "222222222222\n"},
+		{"invalid since", "?format=ids&since=yesterday", http.StatusBadRequest, ""},
+		{"since after until", "?format=ids&since=2025-01-01&until=2024-01-01", http.StatusBadRequest, ""},
+		{"invalid ou-recursive", "?format=ids&ou=Workloads&ou-recursive=maybe", http.StatusBadRequest, ""},
+		{"aws-config without role", "?format=aws-config&name=prod", http.StatusBadRequest, ""},
+		{"aws-config with role", "?format=aws-config&name=prod&role=OrganizationAccountAccessRole", http.StatusOK, "arn:aws:iam::111111111111:role/OrganizationAccountAccessRole"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.handleAccounts(recorder, httptest.NewRequest(http.MethodGet, "/accounts"+tt.query, nil))
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if strings.Contains(tt.query, "format=ids") {
+				if got := recorder.Body.String(); got != tt.wantBody {
+					t.Errorf("body = %q, want %q", got, tt.wantBody)
+				}
+			} else if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Errorf("body does not contain %q:\n%s", tt.wantBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".awsid-aliases")
+	if aliases, err := readAliases(path); err != nil || aliases != nil {
+		t.Fatalf("missing file: readAliases = %v, %v, want no aliases", aliases, err)
+	}
+
+	content := "# Short names for accounts\n\np = prod-main\nstg=staging-environment\np=prod-main-v2\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	aliases, err := readAliases(path)
+	if err != nil {
+		t.Fatalf("readAliases: %v", err)
+	}
+	want := []accountAlias{{"p", "prod-main"}, {"stg", "staging-environment"}, {"p", "prod-main-v2"}}
+	if !slices.Equal(aliases, want) {
+		t.Errorf("readAliases = %v, want %v", aliases, want)
+	}
+
+	// Later lines win, as when the file is edited by hand
+	if target, ok := lookupAlias(aliases, "p"); !ok || target != "prod-main-v2" {
+		t.Errorf("lookupAlias(p) = %q, %v, want prod-main-v2", target, ok)
+	}
+	if _, ok := lookupAlias(aliases, "prod"); ok {
+		t.Errorf("lookupAlias(prod) found an alias that is not defined")
+	}
+
+	for _, line := range []string{"no-separator", "=prod-main", "p="} {
+		if err := os.WriteFile(path, []byte(line+"\n"), 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if _, err := readAliases(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("readAliases(%q) error = %v, want an invalid alias error for line 1", line, err)
+		}
+	}
+}
+
+func TestWriteAliasesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".awsid-aliases")
+	aliases := []accountAlias{{"p", "prod main (Tokyo)"}, {"s", "staging"}}
+	if err := writeAliases(path, aliases); err != nil {
+		t.Fatalf("writeAliases: %v", err)
+	}
+	got, err := readAliases(path)
+	if err != nil {
+		t.Fatalf("readAliases: %v", err)
+	}
+	if !slices.Equal(got, aliases) {
+		t.Errorf("read back %v, want %v", got, aliases)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("alias file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	// The temporary file is renamed over the target, so nothing is left behind
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory has %d entries after writing, want only the alias file", len(entries))
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name, target string
+		valid        bool
+	}{
+		{"p", "prod-main", true},
+		{"prod_1", "prod main", true},
+		{"", "prod-main", false},
+		{"a b", "prod-main", false},
+		{"a=b", "prod-main", false},
+		{"a,b", "prod-main", false},
+		{"#a", "prod-main", false},
+		{"p", "", false},
+		{"p", " prod-main", false},
+		{"p", "prod\nmain", false},
+	}
+	for _, tt := range tests {
+		if err := validateAlias(tt.name, tt.target); (err == nil) != tt.valid {
+			t.Errorf("validateAlias(%q, %q) = %v, want valid %v", tt.name, tt.target, err, tt.valid)
+		}
+	}
+}
+
+func TestSelectAccountsWithAlias(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "111111111111", Name: "prod-main", AliasName: "prod-main"},
+		{ID: "222222222222", Name: "stg-app", AliasName: "stg-app"},
+		{ID: "333333333333", Name: "stg-web", AliasName: "stg-web"},
+	}
+	aliases := []accountAlias{{"p", "prod-main"}, {"stg", "retired-staging"}}
+
+	tests := []struct {
+		name      string
+		term      string
+		want      []string
+		wantExact bool
+		wantName  string // Name the results were selected with
+	}{
+		{"alias with an exact match", "p", []string{"111111111111"}, true, "prod-main"},
+		{"alias target missing falls back to the term", "stg", []string{"222222222222", "333333333333"}, false, "stg"},
+		{"not an alias", "web", []string{"333333333333"}, false, "web"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, exact, criteria := selectAccountsWithAlias(accounts, awsid.Criteria{Query: awsid.Query{Name: tt.term}}, aliases)
+			var ids []string
+			for _, account := range results {
+				ids = append(ids, account.ID)
+			}
+			if !slices.Equal(ids, tt.want) || exact != tt.wantExact {
+				t.Errorf("results = %v (exact %v), want %v (exact %v)", ids, exact, tt.want, tt.wantExact)
+			}
+			if criteria.Name != tt.wantName {
+				t.Errorf("criteria.Name = %q, want %q", criteria.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := historyPath()
+	if path != filepath.Join(os.Getenv("HOME"), ".awsid-history") {
+		t.Fatalf("historyPath = %q, want ~/.awsid-history", path)
+	}
+
+	prod := awsid.AccountInfo{ID: "111111111111", Name: "prod-main"}
+	stg := awsid.AccountInfo{ID: "222222222222", Name: "staging"}
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	// Only a single search mode resolving to exactly one account is recorded
+	for i, search := range []struct {
+		modes   int
+		results []awsid.AccountInfo
+	}{
+		{1, []awsid.AccountInfo{prod}},
+		{0, []awsid.AccountInfo{stg}},       // Listing every account
+		{1, []awsid.AccountInfo{prod, stg}}, // Ambiguous partial match
+		{1, nil},                            // No match
+		{1, []awsid.AccountInfo{stg}},
+		{1, []awsid.AccountInfo{prod}},
+	} {
+		if err := recordSearch(path, search.modes, search.results, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("recordSearch: %v", err)
+		}
+	}
+
+	// Entries are appended as JSON Lines, oldest first
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	wantLines := `{"timestamp":"2026-10-15T09:00:00Z","id":"111111111111","name":"prod-main"}
+{"timestamp":"2026-10-15T09:04:00Z","id":"222222222222","name":"staging"}
+{"timestamp":"2026-10-15T09:05:00Z","id":"111111111111","name":"prod-main"}
+`
+	if string(data) != wantLines {
+		t.Errorf("history file =\n%s\nwant:\n%s", data, wantLines)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("history file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	entries, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory: %v", err)
+	}
+	local := func(minute int) string {
+		return start.Add(time.Duration(minute) * time.Minute).Local().Format("2006-01-02 15:04:05")
+	}
+	for _, tt := range []struct {
+		limit int
+		want  string
+	}{
+		{0, local(5) + "  111111111111  prod-main\n" + local(4) + "  222222222222  staging\n" + local(0) + "  111111111111  prod-main\n"},
+		{2, local(5) + "  111111111111  prod-main\n" + local(4) + "  222222222222  staging\n"},
+		{10, local(5) + "  111111111111  prod-main\n" + local(4) + "  222222222222  staging\n" + local(0) + "  111111111111  prod-main\n"},
+	} {
+		var buf bytes.Buffer
+		if err := writeHistory(&buf, entries, tt.limit); err != nil {
+			t.Fatalf("writeHistory: %v", err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("history with limit %d =\n%s\nwant newest first:\n%s", tt.limit, buf.String(), tt.want)
+		}
+	}
+
+	if err := clearHistory(path); err != nil {
+		t.Fatalf("clearHistory: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("history file still exists after clearing: %v", err)
+	}
+	if err := clearHistory(path); err != nil {
+		t.Errorf("clearing a missing history: %v", err)
+	}
+	if entries, err := readHistory(path); err != nil || len(entries) != 0 {
+		t.Errorf("readHistory after clearing = %v, %v, want an empty history", entries, err)
+	}
+}
+
+func TestReadHistorySkipsInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".awsid-history")
+	content := `{"timestamp":"2026-10-15T09:00:00Z","id":"111111111111","name":"prod-main"}
+{"timestamp":"2026-10-15T09:01:00Z","id":"2222
+{"timestamp":"2026-10-15T09:02:00Z","id":"333333333333","name":"dev"}
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	entries, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "111111111111" || entries[1].ID != "333333333333" {
+		t.Errorf("readHistory = %+v, want the two valid entries", entries)
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	// newCommands returns a root command with search flags and an update
+	// subcommand that only shares --max-age with it
+	newCommands := func() (root, update *cobra.Command) {
+		root = &cobra.Command{Use: "awsid"}
+		root.Flags().String("format", "", "")
+		root.Flags().Bool("json", false, "")
+		root.Flags().Duration("max-age", 0, "")
+		root.Flags().StringArray("tag", nil, "")
+		root.Flags().String("status", "", "")
+		root.Flags().String("config", "", "")
+		update = &cobra.Command{Use: "update"}
+		update.Flags().Duration("max-age", 0, "")
+		root.AddCommand(update)
+		return root, update
+	}
+
+	tests := []struct {
+		name       string
+		config     string
+		subcommand bool
+		args       []string
+		want       map[string]string // Flag values after applying the config
+		wantErr    string
+	}{
+		{"underscores become hyphens", "max_age: 90m\n", false, nil, map[string]string{"max-age": "1h30m0s"}, ""},
+		{"hyphenated keys", "max-age: 2h\nformat: table\n", false, nil, map[string]string{"max-age": "2h0m0s", "format": "table"}, ""},
+		{"explicit flag wins", "format: table\nstatus: ACTIVE\n", false, []string{"--format", "csv"}, map[string]string{"format": "csv", "status": "ACTIVE"}, ""},
+		{"format flag skips the config format", "format: table\n", false, []string{"--json"}, map[string]string{"format": "", "json": "true"}, ""},
+		{"root-only key is skipped for a subcommand", "format: table\nmax_age: 1h\n", true, nil, map[string]string{"max-age": "1h0m0s"}, ""},
+		{"repeatable flag takes a list item by item", "tag:\n  - Env=prod\n  - Team=web\n", false, nil, map[string]string{"tag": "[Env=prod,Team=web]"}, ""},
+		{"other flags take a list comma-joined", "status:\n  - ACTIVE\n  - SUSPENDED\n", false, nil, map[string]string{"status": "ACTIVE,SUSPENDED"}, ""},
+		{"unknown key", "colour: always\n", false, nil, nil, `unknown key "colour"`},
+		{"config cannot point to another config", "config: other.yaml\n", false, nil, nil, `unknown key "config"`},
+		{"invalid value", "max_age: soon\n", false, nil, nil, `invalid value for "max_age"`},
+		{"invalid YAML", "format: [table\n", false, nil, nil, "failed to parse config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "awsid.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			root, update := newCommands()
+			cmd := root
+			if tt.subcommand {
+				cmd = update
+			}
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags: %v", err)
+			}
+
+			err := applyConfigFile(cmd, path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyConfigFile error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyConfigFile: %v", err)
+			}
+			for name, want := range tt.want {
+				if got := cmd.Flags().Lookup(name).Value.String(); got != want {
+					t.Errorf("--%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyConfigFileMissing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cmd := &cobra.Command{Use: "awsid"}
+	cmd.Flags().String("format", "", "")
+
+	// A missing ~/.awsid.yaml is ignored, but a missing --config file is an error
+	if err := applyConfigFile(cmd, ""); err != nil {
+		t.Errorf("missing default config: %v", err)
+	}
+	if err := applyConfigFile(cmd, filepath.Join(os.Getenv("HOME"), "missing.yaml")); err == nil {
+		t.Errorf("missing --config file: want an error")
+	}
+
+	// The default config is read from the home directory
+	if err := os.WriteFile(filepath.Join(os.Getenv("HOME"), ".awsid.yaml"), []byte("format: yaml\n"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := applyConfigFile(cmd, ""); err != nil {
+		t.Fatalf("applyConfigFile: %v", err)
+	}
+	if got := cmd.Flags().Lookup("format").Value.String(); got != "yaml" {
+		t.Errorf("--format = %q, want yaml from ~/.awsid.yaml", got)
+	}
+}
+
+func TestSaveAccountInfoWaitsForCacheLock(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "account_info")
+	original := []awsid.AccountInfo{{ID: "111111111111", Name: "original"}}
+	if err := saveAccountInfoToCSV(filePath, original); err != nil {
+		t.Fatalf("saveAccountInfoToCSV: %v", err)
+	}
+
+	// Another awsid process holding the lock
+	lock := flock.New(filePath + ".lock")
+	if err := lock.Lock(); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	t.Run("times out while the lock is held", func(t *testing.T) {
+		start := time.Now()
+		err := saveAccountInfoToCSV(filePath, []awsid.AccountInfo{{ID: "222222222222", Name: "blocked"}})
+		if !errors.Is(err, errCacheLocked) {
+			t.Fatalf("saveAccountInfoToCSV error = %v, want errCacheLocked", err)
+		}
+		if elapsed := time.Since(start); elapsed < cacheLockTimeout {
+			t.Errorf("gave up after %s, want to wait %s", elapsed, cacheLockTimeout)
+		}
+		cached, err := awsid.ReadAccountInfo(filePath)
+		if err != nil || len(cached) != 1 || cached[0].Name != "original" {
+			t.Errorf("cache changed while locked: %+v, %v", cached, err)
+		}
+	})
+
+	t.Run("proceeds once the lock is released", func(t *testing.T) {
+		go func() {
+			time.Sleep(3 * cacheLockRetryDelay)
+			lock.Unlock()
+		}()
+		if err := saveAccountInfoToCSV(filePath, []awsid.AccountInfo{{ID: "333333333333", Name: "after"}}); err != nil {
+			t.Fatalf("saveAccountInfoToCSV: %v", err)
+		}
+		cached, err := awsid.ReadAccountInfo(filePath)
+		if err != nil || len(cached) != 1 || cached[0].Name != "after" {
+			t.Errorf("cache after the lock was released: %+v, %v", cached, err)
+		}
+	})
+}
+
+func TestValidateAccountInfoFile(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		data := "\xEF\xBB\xBFid,arn,email,name,status,joined_method,joined_timestamp\n" +
+			"# Comments are allowed\n" +
+			"123456789012,arn:aws:organizations::111111111111:account/o-abc/123456789012,prod@example.com,prod,ACTIVE,CREATED,2024-02-24T13:08:50.690000+09:00\n"
+		report, err := validateAccountInfoFile(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("validateAccountInfoFile: %v", err)
+		}
+		if len(report.problems) != 0 || len(report.accounts) != 1 {
+			t.Errorf("problems = %q, accounts = %d, want no problems and 1 account", report.problems, len(report.accounts))
+		}
+	})
+
+	t.Run("problems by line", func(t *testing.T) {
+		data := "id,arn,email,name,status,joined_method,joined_timestamp\n" +
+			"123456789012,arn:a,prod@example.com,prod,ACTIVE,CREATED,2024-02-24T13:08:50+09:00\n" +
+			"12345,arn:b,bad-email,short,ACTIVE,CREATED,2024-02-24T13:08:50+09:00\n" +
+			"223456789012,arn:c, stg@example.com ,stg,CLOSED,CREATED,2024-02-24T13:08:50+09:00\n" +
+			"123456789012,arn:d,dup@example.com,prod-dup,ACTIVE,CREATED,2024-02-24T13:08:50+09:00\n" +
+			"323456789012,,,missing,ACTIVE,,\n" +
+			"423456789012,arn:e,e@example.com,extra,ACTIVE,CREATED,2024-02-24T13:08:50+09:00,x\n" +
+			",,,,,,\n"
+		report, err := validateAccountInfoFile(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("validateAccountInfoFile: %v", err)
+		}
+		want := []string{
+			`line 3 (short): invalid account ID "12345", must be 12 digits`,
+			`line 3 (short): invalid email address "bad-email"`,
+			`line 4: leading or trailing whitespace in a field`,
+			`line 4 (stg): unknown status "CLOSED", expected one of ACTIVE, SUSPENDED, PENDING_CLOSURE`,
+			`line 6 (missing): missing fields: arn, email, joined_method, joined_timestamp`,
+			`line 7: 8 columns, expected 7`,
+			`line 8: no account ID, the row is ignored`,
+			`duplicate account ID 123456789012 on lines 2, 5`,
+		}
+		if !slices.Equal(report.problems, want) {
+			t.Errorf("problems =\n%s\nwant:\n%s", strings.Join(report.problems, "\n"), strings.Join(want, "\n"))
+		}
+		if report.untrimmedRows != 1 {
+			t.Errorf("untrimmedRows = %d, want 1", report.untrimmedRows)
+		}
+		// Parsed rows are trimmed, so --fix can write them back cleaned up
+		if report.accounts[2].Email != "stg@example.com" {
+			t.Errorf("email of the untrimmed row = %q, want it trimmed", report.accounts[2].Email)
+		}
+	})
+
+	t.Run("legacy rows", func(t *testing.T) {
+		report, err := validateAccountInfoFile(strings.NewReader("prod,123456789012\n"))
+		if err != nil {
+			t.Fatalf("validateAccountInfoFile: %v", err)
+		}
+		want := []string{"line 1 (prod): old two-column row, run awsid migrate to fill in the missing fields"}
+		if !slices.Equal(report.problems, want) || report.legacyRows != 1 {
+			t.Errorf("problems = %q, legacyRows = %d, want %q and 1", report.problems, report.legacyRows, want)
+		}
+	})
+
+	t.Run("malformed CSV", func(t *testing.T) {
+		if _, err := validateAccountInfoFile(strings.NewReader("id,name\n\"unterminated,prod\n")); err == nil {
+			t.Errorf("want an error for malformed CSV")
+		}
+	})
+}
+
+func TestDiffAccountInfo(t *testing.T) {
+	oldAccounts := []awsid.AccountInfo{
+		{ID: "111111111111", Name: "prod", Status: "ACTIVE"},
+		{ID: "222222222222", Name: "retired", Status: "ACTIVE"},
+		{ID: "333333333333", Name: "stg", Status: "ACTIVE", Email: "stg@example.com"},
+		{ID: "444444444444", Name: "dev", Status: "ACTIVE", Tags: map[string]string{"Env": "dev"}},
+	}
+	newAccounts := []awsid.AccountInfo{
+		{ID: "555555555555", Name: "sandbox", Status: "ACTIVE"},
+		{ID: "333333333333", Name: "staging", Status: "SUSPENDED", Email: "stg@example.com"},
+		{ID: "111111111111", Name: "prod", Status: "ACTIVE"},
+		{ID: "444444444444", Name: "dev", Status: "ACTIVE", Tags: map[string]string{"Env": "dev", "Team": "web"}},
+	}
+
+	diff := diffAccountInfo(oldAccounts, newAccounts)
+
+	var added, removed []string
+	for _, account := range diff.Added {
+		added = append(added, account.ID)
+	}
+	for _, account := range diff.Removed {
+		removed = append(removed, account.ID)
+	}
+	if !slices.Equal(added, []string{"555555555555"}) {
+		t.Errorf("added = %v, want [555555555555]", added)
+	}
+	if !slices.Equal(removed, []string{"222222222222"}) {
+		t.Errorf("removed = %v, want [222222222222]", removed)
+	}
+
+	var text bytes.Buffer
+	if err := writeDiffText(&text, diff, false); err != nil {
+		t.Fatalf("writeDiffText: %v", err)
+	}
+	wantText := `+ 555555555555 sandbox
+- 222222222222 retired
+~ 333333333333 staging
+    name: stg -> staging
+    status: ACTIVE -> SUSPENDED
+~ 444444444444 dev
+    tags: Env=dev -> Env=dev;Team=web
+`
+	if text.String() != wantText {
+		t.Errorf("diff text =\n%s\nwant:\n%s", text.String(), wantText)
+	}
+
+	var jsonOut bytes.Buffer
+	if err := writeDiffJSON(&jsonOut, diff); err != nil {
+		t.Fatalf("writeDiffJSON: %v", err)
+	}
+	var decoded AccountInfoDiff
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil {
+		t.Fatalf("diff JSON does not decode: %v", err)
+	}
+	if len(decoded.Changed) != 2 || decoded.Changed[0].Changes[1] != (FieldChange{Field: "status", Old: "ACTIVE", New: "SUSPENDED"}) {
+		t.Errorf("decoded changes = %+v", decoded.Changed)
+	}
+
+	// Identical snapshots have no differences, and the JSON lists are empty rather than null
+	same := diffAccountInfo(oldAccounts, oldAccounts)
+	if len(same.Added)+len(same.Removed)+len(same.Changed) != 0 {
+		t.Errorf("diff of identical snapshots = %+v, want none", same)
+	}
+	jsonOut.Reset()
+	if err := writeDiffJSON(&jsonOut, same); err != nil {
+		t.Fatalf("writeDiffJSON: %v", err)
+	}
+	if want := "{\n    \"added\": [],\n    \"removed\": [],\n    \"changed\": []\n}\n"; jsonOut.String() != want {
+		t.Errorf("empty diff JSON = %q, want %q", jsonOut.String(), want)
+	}
+}
+
+func TestComputeAccountStats(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "111111111111", Status: "ACTIVE", JoinedMethod: "CREATED", Email: "a@Example.com"},
+		{ID: "222222222222", Status: "ACTIVE", JoinedMethod: "INVITED", Email: "b@example.com"},
+		{ID: "333333333333", Status: "SUSPENDED", JoinedMethod: "CREATED", Email: "c@other.org"},
+		{ID: "444444444444", Status: "ACTIVE"},
+	}
+
+	stats := computeAccountStats(accounts, true)
+	want := AccountStats{
+		Total:        4,
+		Status:       []StatCount{{"ACTIVE", 3}, {"SUSPENDED", 1}},
+		JoinedMethod: []StatCount{{"CREATED", 2}, {"(none)", 1}, {"INVITED", 1}},
+		EmailDomain:  []StatCount{{"example.com", 2}, {"(none)", 1}, {"other.org", 1}},
+	}
+	if stats.Total != want.Total ||
+		!slices.Equal(stats.Status, want.Status) ||
+		!slices.Equal(stats.JoinedMethod, want.JoinedMethod) ||
+		!slices.Equal(stats.EmailDomain, want.EmailDomain) {
+		t.Errorf("computeAccountStats = %+v, want %+v", stats, want)
+	}
+
+	if stats := computeAccountStats(accounts, false); stats.EmailDomain != nil {
+		t.Errorf("EmailDomain = %v without byEmailDomain, want nil", stats.EmailDomain)
+	}
+
+	var jsonOut bytes.Buffer
+	if err := writeStatsJSON(&jsonOut, computeAccountStats(accounts[:1], false)); err != nil {
+		t.Fatalf("writeStatsJSON: %v", err)
+	}
+	wantJSON := `{
+    "total": 1,
+    "status": [
+        {
+            "value": "ACTIVE",
+            "count": 1
+        }
+    ],
+    "joined_method": [
+        {
+            "value": "CREATED",
+            "count": 1
+        }
+    ]
+}
+`
+	if jsonOut.String() != wantJSON {
+		t.Errorf("stats JSON =\n%s\nwant:\n%s", jsonOut.String(), wantJSON)
+	}
+
+	var table bytes.Buffer
+	if err := writeStatsTable(&table, stats); err != nil {
+		t.Fatalf("writeStatsTable: %v", err)
+	}
+	for _, row := range [][]string{
+		{"total", "4"},
+		{"status", "ACTIVE", "3"},
+		{"joined_method", "(none)", "1"},
+		{"email_domain", "example.com", "2"},
+	} {
+		found := false
+		for _, line := range strings.Split(table.String(), "\n") {
+			if slices.Equal(strings.Fields(strings.ReplaceAll(line, "│", " ")), row) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("stats table has no row %v:\n%s", row, table.String())
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, rawURL := range []string{"", "https://hooks.example.com/x", "http://localhost:8080/hook"} {
+		if err := validateWebhookURL(rawURL); err != nil {
+			t.Errorf("validateWebhookURL(%q) = %v, want nil", rawURL, err)
+		}
+	}
+	for _, rawURL := range []string{"hooks.example.com/x", "ftp://example.com/x", "https://", "://bad"} {
+		if err := validateWebhookURL(rawURL); err == nil {
+			t.Errorf("validateWebhookURL(%q) = nil, want an error", rawURL)
+		}
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	diff := AccountInfoDiff{
+		Added:   []awsid.AccountInfo{{ID: "555555555555", Name: "sandbox"}},
+		Removed: []awsid.AccountInfo{{ID: "222222222222", AliasName: "old-alias"}},
+	}
+	payload := newWebhookPayload("/cache/account_info", diff)
+	wantText := "awsid: accounts changed in /cache/account_info: +1 new, -1 removed\n+ 555555555555 sandbox\n- 222222222222 old-alias"
+	if payload.Text != wantText {
+		t.Errorf("payload text = %q, want %q", payload.Text, wantText)
+	}
+
+	var gotMethod, gotContentType string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotContentType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := postWebhook(server.URL, payload); err != nil {
+		t.Fatalf("postWebhook: %v", err)
+	}
+	if gotMethod != http.MethodPost || gotContentType != "application/json" {
+		t.Errorf("request = %s with Content-Type %q, want POST application/json", gotMethod, gotContentType)
+	}
+	var received map[string]any
+	if err := json.Unmarshal(gotBody, &received); err != nil {
+		t.Fatalf("webhook body is not JSON: %v\n%s", err, gotBody)
+	}
+	if received["text"] != wantText || received["file"] != "/cache/account_info" {
+		t.Errorf("webhook body = %s", gotBody)
+	}
+	added, _ := received["added"].([]any)
+	if len(added) != 1 || added[0].(map[string]any)["id"] != "555555555555" {
+		t.Errorf("webhook added = %v", received["added"])
+	}
+
+	// An empty diff still sends empty lists rather than null
+	gotBody = nil
+	if err := postWebhook(server.URL, newWebhookPayload("f", AccountInfoDiff{})); err != nil {
+		t.Fatalf("postWebhook: %v", err)
+	}
+	if !bytes.Contains(gotBody, []byte(`"added":[],"removed":[]`)) {
+		t.Errorf("empty webhook body = %s", gotBody)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := postWebhook(failing.URL, payload); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("postWebhook to a failing server = %v, want a 500 error", err)
+	}
+}
+
+func TestSQLiteOutputRoundTrip(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "123456789012", Name: "prod", Email: "prod@example.com", Status: "ACTIVE", JoinedMethod: "CREATED", JoinedTimestamp: "2024-02-24T13:08:50+09:00",
+			Tags: map[string]string{"Env": "prod"}, OUName: "Workloads", OUPath: "Root/Workloads"},
+		{ID: "023456789013", Name: "dev", Status: "SUSPENDED"},
+	}
+
+	var buf bytes.Buffer
+	if err := NewOutputManager(&buf).Output(accounts, "sqlite", false); err != nil {
+		t.Fatalf("sqlite output: %v", err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "accounts.db")
+	if err := os.WriteFile(dbPath, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !isSQLiteFile(dbPath) {
+		t.Fatalf("isSQLiteFile(%s) = false for --format sqlite output", dbPath)
+	}
+
+	got, err := readAccountInfoFromSQLite(dbPath)
+	if err != nil {
+		t.Fatalf("readAccountInfoFromSQLite: %v", err)
+	}
+	if len(got) != len(accounts) {
+		t.Fatalf("read %d accounts, want %d", len(got), len(accounts))
+	}
+	for i := range accounts {
+		for _, field := range append(append([]string{}, awsid.AccountFields...), awsid.OptionalFields...) {
+			if got[i].FieldValue(field) != accounts[i].FieldValue(field) {
+				t.Errorf("account %d %s = %q, want %q", i, field, got[i].FieldValue(field), accounts[i].FieldValue(field))
+			}
+		}
+	}
+
+	csvPath := filepath.Join(t.TempDir(), "account_info")
+	if err := os.WriteFile(csvPath, []byte("id,arn,email,name,status,joined_method,joined_timestamp\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if isSQLiteFile(csvPath) || isSQLiteFile(filepath.Join(t.TempDir(), "missing")) {
+		t.Error("isSQLiteFile = true for a CSV or missing file")
+	}
+}
+
+func TestXLSXOutputRoundTrip(t *testing.T) {
+	accounts := []awsid.AccountInfo{
+		{ID: "023456789013", Name: "dev", Status: "SUSPENDED"},
+		{ID: "123456789012", Name: "prod", Email: "prod@example.com", Status: "ACTIVE", JoinedMethod: "CREATED", JoinedTimestamp: "2024-02-24T13:08:50+09:00"},
+	}
+
+	var buf bytes.Buffer
+	if err := NewOutputManager(&buf).Output(accounts, "xlsx", false); err != nil {
+		t.Fatalf("xlsx output: %v", err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("xlsx output does not open: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(xlsxSheetName)
+	if err != nil {
+		t.Fatalf("GetRows(%s): %v", xlsxSheetName, err)
+	}
+	want := [][]string{
+		{"ID", "ARN", "Email", "Name", "Status", "Joined Method", "Joined Timestamp"},
+		{"023456789013", "", "", "dev", "SUSPENDED"},
+		{"123456789012", "", "prod@example.com", "prod", "ACTIVE", "CREATED", "2024-02-24T13:08:50+09:00"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("sheet has %d rows, want %d: %q", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("row %d = %q, want %q", i+1, rows[i], want[i])
+		}
+	}
+
+	// IDs are stored as text so the leading zero survives
+	cellType, err := f.GetCellType(xlsxSheetName, "A2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cellType == excelize.CellTypeNumber {
+		t.Errorf("account ID cell type = number, want text")
+	}
+
+	var noHeader bytes.Buffer
+	m := NewOutputManager(&noHeader)
+	m.NoHeader = true
+	if err := m.Output(accounts, "xlsx", false); err != nil {
+		t.Fatalf("xlsx output: %v", err)
+	}
+	f2, err := excelize.OpenReader(&noHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	if rows, _ := f2.GetRows(xlsxSheetName); len(rows) != 2 || rows[0][0] != "023456789013" {
+		t.Errorf("rows with NoHeader = %q, want the two accounts only", rows)
+	}
+}
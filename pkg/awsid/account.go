@@ -0,0 +1,207 @@
+// Package awsid reads the account info cache written by the awsid CLI
+// (~/.aws/account_info) and searches, filters and sorts the accounts in it,
+// so other Go tools can look up AWS accounts the same way the CLI does.
+package awsid
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccountInfo is an AWS account as stored in the account info cache
+type AccountInfo struct {
+	ID              string `json:"id" yaml:"id"`
+	Arn             string `json:"arn" yaml:"arn"`
+	Email           string `json:"email" yaml:"email"`
+	Name            string `json:"name" yaml:"name"`
+	Status          string `json:"status" yaml:"status"`
+	JoinedMethod    string `json:"joined_method" yaml:"joined_method"`
+	JoinedTimestamp string `json:"joined_timestamp" yaml:"joined_timestamp"`
+	// Organizations tags, only fetched with --with-tags
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Parent organizational unit, only fetched with --with-ou
+	OUID   string `json:"ou_id,omitempty" yaml:"ou_id,omitempty"`
+	OUName string `json:"ou_name,omitempty" yaml:"ou_name,omitempty"`
+	OUPath string `json:"ou_path,omitempty" yaml:"ou_path,omitempty"`
+	// IDs along OUPath, e.g. "r-abcd/ou-abcd-11111111/ou-abcd-22222222"
+	OUIDPath string `json:"ou_id_path,omitempty" yaml:"ou_id_path,omitempty"`
+	// Days since joining the organization, only set with --with-age
+	AgeDays *int `json:"age_days,omitempty" yaml:"age_days,omitempty"`
+	// Cache file the account was read from, only set with --with-source
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// Backward compatibility fields
+	AliasName string `json:"alias_name" yaml:"alias_name"`
+	AccountID string `json:"account_id" yaml:"account_id"`
+	// legacy is set for rows read from the old alias_name,account_id format
+	legacy bool
+}
+
+// AccountFields lists the output columns of an account in their default order
+var AccountFields = []string{"id", "arn", "email", "name", "status", "joined_method", "joined_timestamp"}
+
+// OptionalFields lists columns that are only output when explicitly requested
+// (via --fields or the option that fetches them, e.g. --with-tags)
+var OptionalFields = []string{"tags", "ou_id", "ou_name", "ou_path", "ou_id_path"}
+
+// DerivedFields lists columns computed at output time and never stored in account_info
+var DerivedFields = []string{"age_days", "source"}
+
+// FieldHeaders maps column names to their table header labels
+var FieldHeaders = map[string]string{
+	"id":               "ID",
+	"arn":              "ARN",
+	"email":            "Email",
+	"name":             "Name",
+	"status":           "Status",
+	"joined_method":    "Joined Method",
+	"joined_timestamp": "Joined Timestamp",
+	"tags":             "Tags",
+	"ou_id":            "OU ID",
+	"ou_name":          "OU Name",
+	"ou_path":          "OU Path",
+	"ou_id_path":       "OU ID Path",
+	"age_days":         "Age Days",
+	"source":           "Source",
+}
+
+// FieldValue returns the value of the named output column
+func (a AccountInfo) FieldValue(field string) string {
+	switch field {
+	case "id":
+		return a.ID
+	case "arn":
+		return a.Arn
+	case "email":
+		return a.Email
+	case "name":
+		return a.Name
+	case "status":
+		return a.Status
+	case "joined_method":
+		return a.JoinedMethod
+	case "joined_timestamp":
+		return a.JoinedTimestamp
+	case "tags":
+		return formatTags(a.Tags)
+	case "ou_id":
+		return a.OUID
+	case "ou_name":
+		return a.OUName
+	case "ou_path":
+		return a.OUPath
+	case "ou_id_path":
+		return a.OUIDPath
+	case "age_days":
+		// AgeDays is computed before the joined timestamp is reformatted for display
+		if a.AgeDays != nil {
+			return strconv.Itoa(*a.AgeDays)
+		}
+		if days, ok := DaysSinceJoined(a); ok {
+			return strconv.Itoa(days)
+		}
+	case "source":
+		return a.Source
+	}
+	return ""
+}
+
+// Legacy reports whether the account was read from a row in the old
+// alias_name,account_id format, which lacks every field but the name and ID
+func (a AccountInfo) Legacy() bool {
+	return a.legacy
+}
+
+// AccountName returns the account name, falling back to the alias name
+func AccountName(account AccountInfo) string {
+	if account.Name != "" {
+		return account.Name
+	}
+	return account.AliasName
+}
+
+// ParseTimestamp parses a joined timestamp as stored in account_info
+func ParseTimestamp(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// DaysSinceJoined returns the number of whole days since the account joined.
+// ok is false when the joined timestamp cannot be parsed.
+func DaysSinceJoined(a AccountInfo) (int, bool) {
+	joined, err := ParseTimestamp(a.JoinedTimestamp)
+	if err != nil {
+		return 0, false
+	}
+	return int(time.Since(joined) / (24 * time.Hour)), true
+}
+
+// DedupeAccounts keeps one row per account ID, the last one unless keepFirst
+// is set. Kept rows stay in their original order.
+func DedupeAccounts(accounts []AccountInfo, keepFirst bool) []AccountInfo {
+	kept := make(map[string]int, len(accounts))
+	for i, account := range accounts {
+		if _, ok := kept[account.ID]; ok && keepFirst {
+			continue
+		}
+		kept[account.ID] = i
+	}
+	if len(kept) == len(accounts) {
+		return accounts
+	}
+
+	deduped := make([]AccountInfo, 0, len(kept))
+	for i, account := range accounts {
+		if kept[account.ID] == i {
+			deduped = append(deduped, account)
+		}
+	}
+	return deduped
+}
+
+// setOptionalField sets an optional column read from account_info
+func (a *AccountInfo) setOptionalField(field, value string) {
+	switch field {
+	case "tags":
+		a.Tags = parseTags(value)
+	case "ou_id":
+		a.OUID = value
+	case "ou_name":
+		a.OUName = value
+	case "ou_path":
+		a.OUPath = value
+	case "ou_id_path":
+		a.OUIDPath = value
+	}
+}
+
+// formatTags formats tags as "k=v;k2=v2" sorted by key
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + tags[key]
+	}
+	return strings.Join(pairs, ";")
+}
+
+// parseTags parses tags formatted by formatTags
+func parseTags(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(pair, "=")
+		if key != "" {
+			tags[key] = val
+		}
+	}
+	return tags
+}
@@ -0,0 +1,16 @@
+package awsid
+
+// Criteria selects accounts by their attributes and a search. Fields of the
+// embedded Filter and Query can be set directly, e.g. Criteria{Query: Query{Name: "prod"}}
+// or c.Statuses = []string{"ACTIVE"}.
+type Criteria struct {
+	Filter
+	Query
+}
+
+// SelectAccounts filters accounts with c.Filter and searches the remaining
+// accounts with c.Query, so the filters also narrow down a listing of every
+// account. exact is reported as by SearchAccounts.
+func SelectAccounts(accounts []AccountInfo, c Criteria) (results []AccountInfo, exact bool) {
+	return SearchAccounts(FilterAccounts(accounts, c.Filter), c.Query)
+}
@@ -0,0 +1,201 @@
+package awsid
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testAccounts is a small organization covering every attribute the filters and searches look at
+var testAccounts = []AccountInfo{
+	{
+		ID: "111111111111", Name: "prod-web", AliasName: "prod-web", Email: "web@example.com", Status: "ACTIVE",
+		JoinedTimestamp: "2023-01-15T10:00:00Z", Tags: map[string]string{"Env": "prod", "Team": "web"},
+		OUID: "ou-root-prod", OUName: "Production", OUPath: "Root/Workloads/Production",
+	},
+	{
+		ID: "222222222222", Name: "prod-api", AliasName: "prod-api", Email: "api@corp.example.org", Status: "ACTIVE",
+		JoinedTimestamp: "2024-06-01T00:00:00+09:00", Tags: map[string]string{"Env": "prod", "Team": "api"},
+		OUID: "ou-root-prod", OUName: "Production", OUPath: "Root/Workloads/Production",
+	},
+	{
+		ID: "333333333333", Name: "stg-web", AliasName: "stg-web", Email: "stg@example.com", Status: "ACTIVE",
+		JoinedTimestamp: "2024-09-30T23:59:59Z", Tags: map[string]string{"Env": "stg", "Team": "web"},
+		OUID: "ou-root-stg", OUName: "Staging", OUPath: "Root/Workloads/Staging",
+	},
+	{
+		ID: "444444444444", Name: "Prod-Legacy", AliasName: "Prod-Legacy", Email: "legacy@EXAMPLE.com", Status: "SUSPENDED",
+		JoinedTimestamp: "not-a-time",
+		OUID:            "ou-root-sus", OUName: "Suspended", OUPath: "Root/Suspended",
+	},
+	{
+		ID: "555555555555", Name: "sandbox", AliasName: "sandbox", Status: "ACTIVE",
+		JoinedTimestamp: "2025-03-01T12:00:00Z",
+		OUID:            "r-root", OUName: "Root", OUPath: "Root",
+	},
+}
+
+func ids(accounts []AccountInfo) []string {
+	result := []string{}
+	for _, account := range accounts {
+		result = append(result, account.ID)
+	}
+	return result
+}
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("time.Parse(%q): %v", value, err)
+	}
+	return parsed
+}
+
+func TestFilterAccounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"zero filter keeps all", Filter{}, []string{"111111111111", "222222222222", "333333333333", "444444444444", "555555555555"}},
+		{"status", Filter{Statuses: []string{"SUSPENDED"}}, []string{"444444444444"}},
+		{"any of several statuses", Filter{Statuses: []string{"SUSPENDED", "PENDING_CLOSURE"}}, []string{"444444444444"}},
+		{"email domain", Filter{EmailDomains: []string{"example.com"}}, []string{"111111111111", "333333333333", "444444444444"}},
+		{"email domain with @", Filter{EmailDomains: []string{"@corp.example.org"}}, []string{"222222222222"}},
+		{"email domain is not a suffix match", Filter{EmailDomains: []string{"ample.com"}}, []string{}},
+		{"tag", Filter{Tags: map[string][]string{"Team": {"web"}}}, []string{"111111111111", "333333333333"}},
+		{"tag keys are ANDed", Filter{Tags: map[string][]string{"Team": {"web"}, "Env": {"prod"}}}, []string{"111111111111"}},
+		{"tag values are ORed", Filter{Tags: map[string][]string{"Env": {"prod", "stg"}}}, []string{"111111111111", "222222222222", "333333333333"}},
+		{"OU by name", Filter{OU: "Production"}, []string{"111111111111", "222222222222"}},
+		{"OU by ID", Filter{OU: "ou-root-stg"}, []string{"333333333333"}},
+		{"OU by path", Filter{OU: "Root/Suspended"}, []string{"444444444444"}},
+		{"OU not recursive", Filter{OU: "Workloads"}, []string{}},
+		{"OU recursive by name", Filter{OU: "Workloads", OURecursive: true}, []string{"111111111111", "222222222222", "333333333333"}},
+		{"OU recursive by path", Filter{OU: "Root/Workloads", OURecursive: true}, []string{"111111111111", "222222222222", "333333333333"}},
+		{"since", Filter{JoinedSince: mustTime(t, "2024-06-01T00:00:00Z")}, []string{"333333333333", "555555555555"}},
+		{"since compares instants across zones", Filter{JoinedSince: mustTime(t, "2024-05-31T15:00:00Z")}, []string{"222222222222", "333333333333", "555555555555"}},
+		{"until", Filter{JoinedUntil: mustTime(t, "2024-01-01T00:00:00Z")}, []string{"111111111111"}},
+		{"since and until", Filter{JoinedSince: mustTime(t, "2024-01-01T00:00:00Z"), JoinedUntil: mustTime(t, "2024-12-31T00:00:00Z")}, []string{"222222222222", "333333333333"}},
+		{"combined filters", Filter{Statuses: []string{"ACTIVE"}, EmailDomains: []string{"example.com"}, Tags: map[string][]string{"Team": {"web"}}}, []string{"111111111111", "333333333333"}},
+		{"combined filters with OU and time", Filter{OU: "Workloads", OURecursive: true, JoinedSince: mustTime(t, "2024-01-01T00:00:00Z"), Statuses: []string{"ACTIVE"}}, []string{"222222222222", "333333333333"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(FilterAccounts(testAccounts, tt.filter)); !slices.Equal(got, tt.want) {
+				t.Errorf("FilterAccounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchAccounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     Query
+		want      []string
+		wantExact bool
+	}{
+		{"zero query keeps all", Query{}, []string{"111111111111", "222222222222", "333333333333", "444444444444", "555555555555"}, false},
+		{"name substring", Query{Name: "web"}, []string{"111111111111", "333333333333"}, false},
+		{"exact name takes priority", Query{Name: "prod-web"}, []string{"111111111111"}, true},
+		{"name is case-sensitive", Query{Name: "prod"}, []string{"111111111111", "222222222222"}, false},
+		{"name ignoring case", Query{Name: "prod", IgnoreCase: true}, []string{"111111111111", "222222222222", "444444444444"}, false},
+		{"several names", Query{Names: []string{"api", "sand", "prod-api"}}, []string{"222222222222", "555555555555"}, false},
+		{"exact ID", Query{ID: "333333333333"}, []string{"333333333333"}, true},
+		{"ID prefix", Query{ID: "2222"}, []string{"222222222222"}, false},
+		{"unknown ID", Query{ID: "999999999999"}, []string{}, false},
+		{"all fields", Query{All: "corp.example"}, []string{"222222222222"}, false},
+		{"all fields ignoring case", Query{All: "suspended", IgnoreCase: true}, []string{"444444444444"}, false},
+		{"prefix", Query{Prefix: "prod-"}, []string{"111111111111", "222222222222"}, false},
+		{"suffix", Query{Suffix: "-web"}, []string{"111111111111", "333333333333"}, false},
+		{"prefix and suffix", Query{Prefix: "prod", Suffix: "web"}, []string{"111111111111"}, false},
+		{"prefix ignoring case", Query{Prefix: "PROD-", IgnoreCase: true}, []string{"111111111111", "222222222222", "444444444444"}, false},
+		{"glob", Query{Glob: "*-web"}, []string{"111111111111", "333333333333"}, false},
+		{"glob ignoring case", Query{Glob: "prod-*", IgnoreCase: true}, []string{"111111111111", "222222222222", "444444444444"}, false},
+		{"malformed glob", Query{Glob: "[prod"}, []string{}, false},
+		{"regex", Query{Regex: regexp.MustCompile(`^(prod|stg)-web$`)}, []string{"111111111111", "333333333333"}, false},
+		{"ID takes priority over name", Query{ID: "555555555555", Name: "prod"}, []string{"555555555555"}, true},
+		{"prefix takes priority over regex", Query{Prefix: "stg", Regex: regexp.MustCompile(`prod`)}, []string{"333333333333"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, exact := SearchAccounts(testAccounts, tt.query)
+			if got := ids(results); !slices.Equal(got, tt.want) {
+				t.Errorf("SearchAccounts() = %v, want %v", got, tt.want)
+			}
+			if exact != tt.wantExact {
+				t.Errorf("SearchAccounts() exact = %v, want %v", exact, tt.wantExact)
+			}
+		})
+	}
+}
+
+func TestSelectAccounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		criteria  Criteria
+		want      []string
+		wantExact bool
+	}{
+		{"status and name", Criteria{Filter: Filter{Statuses: []string{"ACTIVE"}}, Query: Query{Name: "prod", IgnoreCase: true}}, []string{"111111111111", "222222222222"}, false},
+		{"OU and exact name", Criteria{Filter: Filter{OU: "Production"}, Query: Query{Name: "prod-api"}}, []string{"222222222222"}, true},
+		{"filter removes the exact match", Criteria{Filter: Filter{OU: "Staging"}, Query: Query{Name: "prod-api"}}, []string{}, false},
+		{"tag and suffix", Criteria{Filter: Filter{Tags: map[string][]string{"Env": {"prod"}}}, Query: Query{Suffix: "-api"}}, []string{"222222222222"}, false},
+		{"since and glob", Criteria{Filter: Filter{JoinedSince: mustTime(t, "2024-01-01T00:00:00Z")}, Query: Query{Glob: "*"}}, []string{"222222222222", "333333333333", "555555555555"}, false},
+		{"email domain and regex", Criteria{Filter: Filter{EmailDomains: []string{"example.com"}}, Query: Query{Regex: regexp.MustCompile(`web`)}}, []string{"111111111111", "333333333333"}, false},
+		{"until and ID prefix", Criteria{Filter: Filter{JoinedUntil: mustTime(t, "2024-12-31T00:00:00Z")}, Query: Query{ID: "1"}}, []string{"111111111111"}, false},
+		{"filters only list every match", Criteria{Filter: Filter{OU: "Workloads", OURecursive: true, Tags: map[string][]string{"Team": {"web"}}}}, []string{"111111111111", "333333333333"}, false},
+		{"prefix with status filter", Criteria{Filter: Filter{Statuses: []string{"SUSPENDED"}}, Query: Query{Prefix: "prod", IgnoreCase: true}}, []string{"444444444444"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, exact := SelectAccounts(testAccounts, tt.criteria)
+			if got := ids(results); !slices.Equal(got, tt.want) {
+				t.Errorf("SelectAccounts() = %v, want %v", got, tt.want)
+			}
+			if exact != tt.wantExact {
+				t.Errorf("SelectAccounts() exact = %v, want %v", exact, tt.wantExact)
+			}
+		})
+	}
+}
+
+func TestFilterAccountsOURecursive(t *testing.T) {
+	// Two OUs are named Production: one under Workloads, one under Sandbox
+	accounts := []AccountInfo{
+		{ID: "111111111111", OUID: "ou-ab-prod", OUName: "Production", OUPath: "Root/Workloads/Production", OUIDPath: "r-ab/ou-ab-work/ou-ab-prod"},
+		{ID: "222222222222", OUID: "ou-ab-web", OUName: "Web", OUPath: "Root/Workloads/Production/Web", OUIDPath: "r-ab/ou-ab-work/ou-ab-prod/ou-ab-web"},
+		{ID: "333333333333", OUID: "ou-ab-sbprod", OUName: "Production", OUPath: "Root/Sandbox/Production", OUIDPath: "r-ab/ou-ab-sand/ou-ab-sbprod"},
+		{ID: "444444444444", OUID: "ou-ab-trial", OUName: "Trial", OUPath: "Root/Sandbox/Production/Trial", OUIDPath: "r-ab/ou-ab-sand/ou-ab-sbprod/ou-ab-trial"},
+		{ID: "555555555555", OUID: "ou-ab-stg", OUName: "Staging", OUPath: "Root/Workloads/Staging", OUIDPath: "r-ab/ou-ab-work/ou-ab-stg"},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"ID", Filter{OU: "ou-ab-prod"}, []string{"111111111111"}},
+		{"ID recursive", Filter{OU: "ou-ab-prod", OURecursive: true}, []string{"111111111111", "222222222222"}},
+		{"top-level ID recursive", Filter{OU: "ou-ab-work", OURecursive: true}, []string{"111111111111", "222222222222", "555555555555"}},
+		{"root ID recursive", Filter{OU: "r-ab", OURecursive: true}, []string{"111111111111", "222222222222", "333333333333", "444444444444", "555555555555"}},
+		{"path recursive skips the same name elsewhere", Filter{OU: "Root/Sandbox/Production", OURecursive: true}, []string{"333333333333", "444444444444"}},
+		{"ID recursive skips the same name elsewhere", Filter{OU: "ou-ab-sbprod", OURecursive: true}, []string{"333333333333", "444444444444"}},
+		{"name matches every OU with the name", Filter{OU: "Production", OURecursive: true}, []string{"111111111111", "222222222222", "333333333333", "444444444444"}},
+		{"name recursive", Filter{OU: "Sandbox", OURecursive: true}, []string{"333333333333", "444444444444"}},
+		{"partial path is not a prefix", Filter{OU: "Root/Work", OURecursive: true}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(FilterAccounts(accounts, tt.filter)); !slices.Equal(got, tt.want) {
+				t.Errorf("FilterAccounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package awsid
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filter narrows down accounts by their attributes. Zero fields keep every account.
+type Filter struct {
+	Statuses     []string            // Account statuses, e.g. ACTIVE
+	EmailDomains []string            // Email domains, with or without a leading @
+	Tags         map[string][]string // Values accepted for each tag key; every key must match
+	OU           string              // Parent OU name, ID or path
+	OURecursive  bool                // Also keep accounts in any OU below OU
+	JoinedSince  time.Time           // Earliest joined time
+	JoinedUntil  time.Time           // Latest joined time
+}
+
+// FilterAccounts keeps the accounts that match every condition of f
+func FilterAccounts(accounts []AccountInfo, f Filter) []AccountInfo {
+	accounts = filterByStatus(accounts, f.Statuses)
+	accounts = filterByEmailDomain(accounts, f.EmailDomains)
+	accounts = filterByTags(accounts, f.Tags)
+	accounts = filterByOU(accounts, f.OU, f.OURecursive)
+	return filterByJoinedTime(accounts, f.JoinedSince, f.JoinedUntil)
+}
+
+// filterByJoinedTime keeps accounts that joined within [since, until]. A zero
+// bound is open. When any bound is set, accounts whose joined timestamp cannot
+// be parsed are dropped.
+func filterByJoinedTime(accounts []AccountInfo, since, until time.Time) []AccountInfo {
+	if since.IsZero() && until.IsZero() {
+		return accounts
+	}
+
+	filtered := []AccountInfo{}
+	for _, account := range accounts {
+		joined, err := ParseTimestamp(account.JoinedTimestamp)
+		if err != nil {
+			continue
+		}
+		if !since.IsZero() && joined.Before(since) {
+			continue
+		}
+		if !until.IsZero() && joined.After(until) {
+			continue
+		}
+		filtered = append(filtered, account)
+	}
+	return filtered
+}
+
+// filterByStatus keeps accounts whose status is one of the given statuses.
+// All accounts are kept when no status is given.
+func filterByStatus(accounts []AccountInfo, statuses []string) []AccountInfo {
+	if len(statuses) == 0 {
+		return accounts
+	}
+
+	filtered := []AccountInfo{}
+	for _, account := range accounts {
+		for _, status := range statuses {
+			if account.Status == status {
+				filtered = append(filtered, account)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// filterByEmailDomain keeps accounts whose email belongs to one of the given domains.
+// Accounts without an email never match. All accounts are kept when no domain is given.
+func filterByEmailDomain(accounts []AccountInfo, domains []string) []AccountInfo {
+	if len(domains) == 0 {
+		return accounts
+	}
+
+	filtered := []AccountInfo{}
+	for _, account := range accounts {
+		email := strings.ToLower(account.Email)
+		if email == "" {
+			continue
+		}
+		for _, domain := range domains {
+			domain = strings.ToLower(strings.TrimPrefix(domain, "@"))
+			if strings.HasSuffix(email, "@"+domain) {
+				filtered = append(filtered, account)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// filterByTags keeps accounts that match every tag key in the filter, where
+// any of the values given for a key may match. Accounts missing a key are dropped.
+func filterByTags(accounts []AccountInfo, tagFilter map[string][]string) []AccountInfo {
+	if len(tagFilter) == 0 {
+		return accounts
+	}
+
+	filtered := []AccountInfo{}
+	for _, account := range accounts {
+		matchesAll := true
+		for key, values := range tagFilter {
+			actual, ok := account.Tags[key]
+			matchesKey := false
+			for _, value := range values {
+				if ok && actual == value {
+					matchesKey = true
+					break
+				}
+			}
+			if !matchesKey {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			filtered = append(filtered, account)
+		}
+	}
+	return filtered
+}
+
+// filterByOU keeps accounts whose parent OU matches by name, ID or path. With
+// recursive, accounts in any OU below a matching OU are kept as well. A name
+// matches every OU with that name; an ID or path selects a single OU.
+func filterByOU(accounts []AccountInfo, ou string, recursive bool) []AccountInfo {
+	if ou == "" {
+		return accounts
+	}
+
+	filtered := []AccountInfo{}
+	for _, account := range accounts {
+		if account.OUID == ou || account.OUName == ou || account.OUPath == ou {
+			filtered = append(filtered, account)
+			continue
+		}
+		if recursive && hasAncestorOU(account, ou) {
+			filtered = append(filtered, account)
+		}
+	}
+	return filtered
+}
+
+// hasAncestorOU reports whether the OU given by name, ID or path is above the
+// parent OU of an account
+func hasAncestorOU(account AccountInfo, ou string) bool {
+	if strings.Contains(ou, "/") {
+		return strings.HasPrefix(account.OUPath, ou+"/")
+	}
+	// IDs are only known for caches written with ou_id_path
+	if account.OUIDPath != "" && slices.Contains(strings.Split(account.OUIDPath, "/"), ou) {
+		return true
+	}
+	return slices.Contains(strings.Split(account.OUPath, "/"), ou)
+}
@@ -0,0 +1,49 @@
+package awsid
+
+import (
+	"io"
+	"sync"
+)
+
+// FormatFunc writes accounts to w in an output format
+type FormatFunc func(w io.Writer, accounts []AccountInfo) error
+
+var (
+	formatsMu   sync.RWMutex
+	formats     = make(map[string]FormatFunc)
+	formatNames []string
+)
+
+// RegisterFormat makes an output format available under name, e.g. to
+// --format of the CLI. It is meant to be called from init functions and
+// panics if fn is nil or name is empty or already registered.
+func RegisterFormat(name string, fn FormatFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if name == "" {
+		panic("awsid: RegisterFormat called with an empty name")
+	}
+	if fn == nil {
+		panic("awsid: RegisterFormat called with a nil function for format " + name)
+	}
+	if _, dup := formats[name]; dup {
+		panic("awsid: RegisterFormat called twice for format " + name)
+	}
+	formats[name] = fn
+	formatNames = append(formatNames, name)
+}
+
+// LookupFormat returns the function registered for the named format
+func LookupFormat(name string) (FormatFunc, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	fn, ok := formats[name]
+	return fn, ok
+}
+
+// Formats returns the names of the registered formats in registration order
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return append([]string{}, formatNames...)
+}
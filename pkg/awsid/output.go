@@ -0,0 +1,8 @@
+package awsid
+
+// OutputManager renders a list of accounts in a given output format.
+// isExactMatch is the exact result of SearchAccounts, which formats such as
+// the CLI's default one use to print only the ID of a single exact match.
+type OutputManager interface {
+	Output(accounts []AccountInfo, format string, isExactMatch bool) error
+}
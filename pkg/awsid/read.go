@@ -0,0 +1,145 @@
+package awsid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// legacyHeaderNames are header names used by the old two-column format
+var legacyHeaderNames = []string{"alias_name", "AliasName", "account_id", "AccountID"}
+
+// ReadAccountInfo reads an account info cache file in the current or old
+// two-column format
+func ReadAccountInfo(filePath string) ([]AccountInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseAccountInfo(file)
+}
+
+// ParseAccountInfo parses account info CSV in the current or old two-column format
+func ParseAccountInfo(r io.Reader) ([]AccountInfo, error) {
+	accounts := []AccountInfo{}
+
+	// Read as CSV
+	csvReader := csv.NewReader(SkipBOM(r))
+	csvReader.Comment = '#'
+	csvReader.TrimLeadingSpace = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+	}
+
+	// Positions of optional columns, taken from the header row
+	optionalColumns := make(map[string]int)
+
+	// Process CSV records
+	for i, record := range records {
+		// Skip header row only if every column is a known header name, so an
+		// account aliased "id" on the first row is still read as data
+		if i == 0 && IsHeaderRow(record) {
+			optionalColumns = HeaderOptionalColumns(record)
+			continue
+		}
+
+		if account, ok := ParseAccountRecord(record, optionalColumns); ok {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return accounts, nil
+}
+
+// IsHeaderRow reports whether every column of record is a known header name
+func IsHeaderRow(record []string) bool {
+	if len(record) == 0 {
+		return false
+	}
+	known := make(map[string]bool)
+	for _, names := range [][]string{AccountFields, OptionalFields, legacyHeaderNames} {
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	for _, column := range record {
+		if !known[strings.TrimSpace(column)] {
+			return false
+		}
+	}
+	return true
+}
+
+// HeaderOptionalColumns returns the positions of optional columns in a header row
+func HeaderOptionalColumns(header []string) map[string]int {
+	optionalColumns := make(map[string]int)
+	for index, column := range header {
+		for _, field := range OptionalFields {
+			if strings.TrimSpace(column) == field {
+				optionalColumns[field] = index
+			}
+		}
+	}
+	return optionalColumns
+}
+
+// ParseAccountRecord converts a data row in the current or old two-column
+// format to an account. ok is false for rows without an account ID.
+func ParseAccountRecord(record []string, optionalColumns map[string]int) (AccountInfo, bool) {
+	if len(record) < 2 || strings.TrimSpace(record[0]) == "" {
+		return AccountInfo{}, false
+	}
+
+	var account AccountInfo
+
+	// Check if this is the new format (7 columns) or old format (2 columns)
+	if len(record) >= 7 {
+		// New format: id, arn, email, name, status, joined_method, joined_timestamp
+		account = AccountInfo{
+			ID:              strings.TrimSpace(record[0]),
+			Arn:             strings.TrimSpace(record[1]),
+			Email:           strings.TrimSpace(record[2]),
+			Name:            strings.TrimSpace(record[3]),
+			Status:          strings.TrimSpace(record[4]),
+			JoinedMethod:    strings.TrimSpace(record[5]),
+			JoinedTimestamp: strings.TrimSpace(record[6]),
+			// Backward compatibility
+			AliasName: strings.TrimSpace(record[3]), // Name -> AliasName
+			AccountID: strings.TrimSpace(record[0]), // ID -> AccountID
+		}
+		for field, index := range optionalColumns {
+			if index < len(record) {
+				account.setOptionalField(field, strings.TrimSpace(record[index]))
+			}
+		}
+	} else {
+		// Old format: alias_name, account_id
+		account = AccountInfo{
+			ID:        strings.TrimSpace(record[1]), // account_id -> ID
+			Name:      strings.TrimSpace(record[0]), // alias_name -> Name
+			AliasName: strings.TrimSpace(record[0]),
+			AccountID: strings.TrimSpace(record[1]),
+			legacy:    true,
+		}
+	}
+
+	return account, account.ID != ""
+}
+
+// SkipBOM drops a leading UTF-8 byte order mark, as written by Excel, so the
+// header row is still recognized
+func SkipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+	return br
+}
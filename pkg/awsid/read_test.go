@@ -0,0 +1,52 @@
+package awsid
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadAccountInfoWithBOM(t *testing.T) {
+	// Excel saves "CSV UTF-8" with a byte order mark before the header
+	data := "\xEF\xBB\xBFid,arn,email,name,status,joined_method,joined_timestamp\n" +
+		"123456789012,,prod@example.com,本番,ACTIVE,CREATED,2024-02-24T13:08:50+09:00\n"
+	filePath := filepath.Join(t.TempDir(), "account_info")
+	if err := os.WriteFile(filePath, []byte(data), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	accounts, err := ReadAccountInfo(filePath)
+	if err != nil {
+		t.Fatalf("ReadAccountInfo: %v", err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("got %d accounts, want 1 (the header must not be read as an account)", len(accounts))
+	}
+	if got := accounts[0]; got.ID != "123456789012" || got.Name != "本番" || got.Email != "prod@example.com" {
+		t.Errorf("unexpected account: %+v", got)
+	}
+}
+
+func TestParseAccountInfoQuotedFields(t *testing.T) {
+	data := `id,arn,email,name,status,joined_method,joined_timestamp
+123456789012,,a@example.com,"Prod, Tokyo",ACTIVE,CREATED,
+223456789012,,b@example.com,"The ""main"" account",ACTIVE,INVITED,
+`
+	accounts, err := ParseAccountInfo(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseAccountInfo: %v", err)
+	}
+	want := []string{"Prod, Tokyo", `The "main" account`}
+	if len(accounts) != len(want) {
+		t.Fatalf("got %d accounts, want %d", len(accounts), len(want))
+	}
+	for i, name := range want {
+		if accounts[i].Name != name || accounts[i].AliasName != name {
+			t.Errorf("account %d name = %q, alias = %q, want %q", i, accounts[i].Name, accounts[i].AliasName, name)
+		}
+	}
+	if accounts[1].JoinedMethod != "INVITED" {
+		t.Errorf("columns after a quoted name shifted: %+v", accounts[1])
+	}
+}
@@ -0,0 +1,183 @@
+package awsid
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Query describes an account search. Only the first non-empty search field,
+// in the order they are declared, is used. A zero Query matches every account.
+type Query struct {
+	ID         string         // Exact account ID, or an ID prefix when no account matches exactly
+	All        string         // Substring of the ID, ARN, email, name or status
+	Prefix     string         // Alias name prefix, combined with Suffix when both are set
+	Suffix     string         // Alias name suffix
+	Glob       string         // path.Match pattern for the alias name
+	Regex      *regexp.Regexp // Regular expression for the alias name
+	Names      []string       // Substrings of the alias name, any of which may match
+	Name       string         // Substring of the alias name; exact matches take priority
+	IgnoreCase bool           // Match names, text and patterns case-insensitively; not used for ID and Regex
+}
+
+// SearchAccounts returns the accounts matching q. exact reports that the
+// results are exact ID or Name matches rather than partial matches.
+func SearchAccounts(accounts []AccountInfo, q Query) (results []AccountInfo, exact bool) {
+	switch {
+	case q.ID != "":
+		return searchByID(accounts, q.ID)
+	case q.All != "":
+		return searchAllFields(accounts, q.All, q.IgnoreCase), false
+	case q.Prefix != "" || q.Suffix != "":
+		return searchByAffix(accounts, q.Prefix, q.Suffix, q.IgnoreCase), false
+	case q.Glob != "":
+		return searchByGlob(accounts, q.Glob, q.IgnoreCase), false
+	case q.Regex != nil:
+		return searchByRegex(accounts, q.Regex), false
+	case len(q.Names) > 0:
+		return searchByNames(accounts, q.Names, q.IgnoreCase), false
+	case q.Name != "":
+		matchingAccounts, exactMatch := searchByName(accounts, q.Name, q.IgnoreCase)
+		if len(exactMatch) > 0 {
+			return exactMatch, true
+		}
+		return matchingAccounts, false
+	}
+	return accounts, false
+}
+
+// searchByName returns accounts whose alias name contains the search term,
+// along with the exact matches among them
+func searchByName(accounts []AccountInfo, searchTerm string, ignoreCase bool) ([]AccountInfo, []AccountInfo) {
+	normalize := func(value string) string {
+		if ignoreCase {
+			return strings.ToLower(value)
+		}
+		return value
+	}
+	term := normalize(searchTerm)
+
+	matchingAccounts := []AccountInfo{}
+	for _, account := range accounts {
+		if strings.Contains(normalize(account.AliasName), term) {
+			matchingAccounts = append(matchingAccounts, account)
+		}
+	}
+
+	// Collect every exact match; duplicate aliases are possible in old-format files
+	exactMatch := []AccountInfo{}
+	for _, account := range matchingAccounts {
+		if normalize(account.AliasName) == term {
+			exactMatch = append(exactMatch, account)
+		}
+	}
+
+	return matchingAccounts, exactMatch
+}
+
+// searchByNames returns accounts whose alias name contains any of the search terms,
+// deduplicated by account ID
+func searchByNames(accounts []AccountInfo, searchTerms []string, ignoreCase bool) []AccountInfo {
+	matchingAccounts := []AccountInfo{}
+	seen := make(map[string]bool)
+	for _, term := range searchTerms {
+		matches, _ := searchByName(accounts, term, ignoreCase)
+		for _, account := range matches {
+			if seen[account.ID] {
+				continue
+			}
+			seen[account.ID] = true
+			matchingAccounts = append(matchingAccounts, account)
+		}
+	}
+	return matchingAccounts
+}
+
+// searchAllFields returns accounts where any of ID, ARN, email, name or status contains the term
+func searchAllFields(accounts []AccountInfo, searchTerm string, ignoreCase bool) []AccountInfo {
+	term := searchTerm
+	if ignoreCase {
+		term = strings.ToLower(term)
+	}
+
+	matchingAccounts := []AccountInfo{}
+	for _, account := range accounts {
+		for _, value := range []string{account.ID, account.Arn, account.Email, account.Name, account.Status} {
+			if ignoreCase {
+				value = strings.ToLower(value)
+			}
+			if strings.Contains(value, term) {
+				matchingAccounts = append(matchingAccounts, account)
+				break
+			}
+		}
+	}
+	return matchingAccounts
+}
+
+// searchByGlob returns accounts whose alias name matches the glob pattern.
+// A malformed pattern matches no account.
+func searchByGlob(accounts []AccountInfo, pattern string, ignoreCase bool) []AccountInfo {
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+
+	matchingAccounts := []AccountInfo{}
+	for _, account := range accounts {
+		name := account.AliasName
+		if ignoreCase {
+			name = strings.ToLower(name)
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			matchingAccounts = append(matchingAccounts, account)
+		}
+	}
+	return matchingAccounts
+}
+
+// searchByAffix returns accounts whose alias name starts with prefix and ends
+// with suffix. An empty prefix or suffix matches any name.
+func searchByAffix(accounts []AccountInfo, prefix, suffix string, ignoreCase bool) []AccountInfo {
+	if ignoreCase {
+		prefix = strings.ToLower(prefix)
+		suffix = strings.ToLower(suffix)
+	}
+
+	matchingAccounts := []AccountInfo{}
+	for _, account := range accounts {
+		name := account.AliasName
+		if ignoreCase {
+			name = strings.ToLower(name)
+		}
+		// Require the name to be long enough that prefix and suffix do not overlap
+		if len(name) >= len(prefix)+len(suffix) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix) {
+			matchingAccounts = append(matchingAccounts, account)
+		}
+	}
+	return matchingAccounts
+}
+
+// searchByRegex returns accounts whose alias name matches the regular expression
+func searchByRegex(accounts []AccountInfo, re *regexp.Regexp) []AccountInfo {
+	matchingAccounts := []AccountInfo{}
+	for _, account := range accounts {
+		if re.MatchString(account.AliasName) {
+			matchingAccounts = append(matchingAccounts, account)
+		}
+	}
+	return matchingAccounts
+}
+
+// searchByID finds accounts by ID. An exact match takes priority over prefix matches.
+func searchByID(accounts []AccountInfo, id string) ([]AccountInfo, bool) {
+	prefixMatches := []AccountInfo{}
+	for _, account := range accounts {
+		if account.ID == id {
+			return []AccountInfo{account}, true
+		}
+		if strings.HasPrefix(account.ID, id) {
+			prefixMatches = append(prefixMatches, account)
+		}
+	}
+	return prefixMatches, false
+}
@@ -0,0 +1,168 @@
+package awsid
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortKey is a single sort field and its direction
+type SortKey struct {
+	Field      string
+	Descending bool
+	Natural    bool // Compare runs of digits in names by their numeric value, so name-2 comes before name-10
+}
+
+// ValidSortFields lists the fields accounts can be sorted by
+var ValidSortFields = []string{"id", "name", "email", "status", "joined_timestamp", "joined_method", "age_days"}
+
+// SortAccounts sorts accounts in place by the keys, applied in order. Accounts
+// equal on every key are ordered by ascending ID, so the result does not depend
+// on the input order, and accounts without a comparable value for a key, such
+// as an unparsable joined timestamp, come last regardless of direction.
+func SortAccounts(accounts []AccountInfo, keys []SortKey) {
+	if len(keys) == 0 {
+		return // No sorting required
+	}
+
+	sort.SliceStable(accounts, func(i, j int) bool {
+		for _, key := range keys {
+			sortableI := hasSortableValue(accounts[i], key.Field)
+			sortableJ := hasSortableValue(accounts[j], key.Field)
+			if sortableI != sortableJ {
+				return sortableI
+			}
+
+			result := compareField(accounts[i], accounts[j], key)
+			if result == 0 {
+				continue // Tie on this key, fall through to the next one
+			}
+
+			// Reverse for descending order
+			if key.Descending {
+				return result > 0
+			}
+			return result < 0
+		}
+		// Ties on every key fall back to the ID; sorting is stable for duplicate IDs
+		return compareIDs(accounts[i].ID, accounts[j].ID) < 0
+	})
+}
+
+// compareField compares two accounts by the field of a sort key, returning -1,
+// 0 or 1. Unknown fields compare equal.
+func compareField(a, b AccountInfo, key SortKey) int {
+	switch key.Field {
+	case "id":
+		return compareIDs(a.ID, b.ID)
+	case "name":
+		if key.Natural {
+			return compareNatural(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case "email":
+		return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+	case "status":
+		return strings.Compare(a.Status, b.Status)
+	case "joined_timestamp":
+		return compareTimestamps(a.JoinedTimestamp, b.JoinedTimestamp)
+	case "joined_method":
+		return strings.Compare(a.JoinedMethod, b.JoinedMethod)
+	case "age_days":
+		// Older accounts have more days, so the order is the reverse of joined_timestamp
+		return compareTimestamps(b.JoinedTimestamp, a.JoinedTimestamp)
+	default:
+		return 0
+	}
+}
+
+// compareIDs compares account IDs numerically, falling back to string comparison
+// when either ID is not a number or both have the same numeric value
+func compareIDs(a, b string) int {
+	numA, errA := strconv.ParseUint(a, 10, 64)
+	numB, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil && numA != numB {
+		if numA < numB {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNatural compares strings split into runs of digits and non-digits.
+// Digit runs compare by numeric value, ignoring leading zeros, and other runs
+// compare as strings, so "name-2" sorts before "name-10". Strings equal by
+// value, such as "a01" and "a1", fall back to string comparison.
+func compareNatural(a, b string) int {
+	restA, restB := a, b
+	for restA != "" && restB != "" {
+		var chunkA, chunkB string
+		chunkA, restA = nextNaturalChunk(restA)
+		chunkB, restB = nextNaturalChunk(restB)
+
+		if isDigit(chunkA[0]) && isDigit(chunkB[0]) {
+			// Compare digit runs by length without leading zeros, then digit by digit,
+			// which avoids overflowing on long numbers
+			trimmedA := strings.TrimLeft(chunkA, "0")
+			trimmedB := strings.TrimLeft(chunkB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				if len(trimmedA) < len(trimmedB) {
+					return -1
+				}
+				return 1
+			}
+			if result := strings.Compare(trimmedA, trimmedB); result != 0 {
+				return result
+			}
+			continue
+		}
+		if result := strings.Compare(chunkA, chunkB); result != 0 {
+			return result
+		}
+	}
+
+	if restA == "" && restB != "" {
+		return -1
+	}
+	if restA != "" && restB == "" {
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// nextNaturalChunk splits off the leading run of digits or non-digits of s
+func nextNaturalChunk(s string) (chunk, rest string) {
+	digits := isDigit(s[0])
+	end := 1
+	for end < len(s) && isDigit(s[end]) == digits {
+		end++
+	}
+	return s[:end], s[end:]
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// compareTimestamps compares timestamps chronologically, falling back to string
+// comparison when either value cannot be parsed
+func compareTimestamps(a, b string) int {
+	timeA, errA := ParseTimestamp(a)
+	timeB, errB := ParseTimestamp(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return timeA.Compare(timeB)
+}
+
+// hasSortableValue reports whether an account has a comparable value for the field.
+// Accounts without one are sorted last regardless of direction.
+func hasSortableValue(account AccountInfo, field string) bool {
+	if field == "joined_timestamp" || field == "age_days" {
+		_, err := ParseTimestamp(account.JoinedTimestamp)
+		return err == nil
+	}
+	return true
+}
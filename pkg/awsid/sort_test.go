@@ -0,0 +1,105 @@
+package awsid
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortAccountsByIDWithLeadingZeros(t *testing.T) {
+	accounts := []AccountInfo{
+		{ID: "123456789012"},
+		{ID: "023456789013"},
+		{ID: "23456789013"}, // Leading zero lost, e.g. after a round trip through a spreadsheet
+		{ID: "003456789014"},
+		{ID: "99"},
+	}
+
+	SortAccounts(accounts, []SortKey{{Field: "id"}})
+	want := []string{"99", "003456789014", "023456789013", "23456789013", "123456789012"}
+	if got := ids(accounts); !slices.Equal(got, want) {
+		t.Errorf("ascending = %v, want %v", got, want)
+	}
+
+	SortAccounts(accounts, []SortKey{{Field: "id", Descending: true}})
+	want = []string{"123456789012", "23456789013", "023456789013", "003456789014", "99"}
+	if got := ids(accounts); !slices.Equal(got, want) {
+		t.Errorf("descending = %v, want %v", got, want)
+	}
+}
+
+func TestSortAccountsUnparsableTimestampsLast(t *testing.T) {
+	newAccounts := func() []AccountInfo {
+		return []AccountInfo{
+			{ID: "111111111111", JoinedTimestamp: "not-a-time"},
+			{ID: "222222222222", JoinedTimestamp: "2023-01-01T00:00:00Z"},
+			{ID: "333333333333", JoinedTimestamp: ""},
+			{ID: "444444444444", JoinedTimestamp: "2024-01-01T09:00:00+09:00"},
+			{ID: "555555555555", JoinedTimestamp: "2022-06-01T00:00:00Z"},
+		}
+	}
+
+	// Accounts with an unparsable timestamp compare by the raw string among
+	// themselves, so only their position after the others is checked
+	unparsable := []string{"111111111111", "333333333333"}
+
+	tests := []struct {
+		name string
+		key  SortKey
+		want []string // Accounts with a parsable timestamp, in order
+	}{
+		{"joined_timestamp ascending", SortKey{Field: "joined_timestamp"}, []string{"555555555555", "222222222222", "444444444444"}},
+		{"joined_timestamp descending", SortKey{Field: "joined_timestamp", Descending: true}, []string{"444444444444", "222222222222", "555555555555"}},
+		{"age_days ascending", SortKey{Field: "age_days"}, []string{"444444444444", "222222222222", "555555555555"}},
+		{"age_days descending", SortKey{Field: "age_days", Descending: true}, []string{"555555555555", "222222222222", "444444444444"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := newAccounts()
+			SortAccounts(accounts, []SortKey{tt.key})
+			got := ids(accounts)
+			if !slices.Equal(got[:len(tt.want)], tt.want) {
+				t.Errorf("SortAccounts() = %v, want %v first", got, tt.want)
+			}
+			last := slices.Sorted(slices.Values(got[len(tt.want):]))
+			if !slices.Equal(last, unparsable) {
+				t.Errorf("SortAccounts() = %v, want %v last", got, unparsable)
+			}
+		})
+	}
+}
+
+func TestSortAccountsTieBreaksByID(t *testing.T) {
+	accounts := []AccountInfo{
+		{ID: "555555555555", Name: "b", Status: "ACTIVE"},
+		{ID: "111111111111", Name: "a", Status: "ACTIVE"},
+		{ID: "444444444444", Name: "b", Status: "SUSPENDED"},
+		{ID: "033333333333", Name: "b", Status: "ACTIVE"},
+		{ID: "222222222222", Name: "a", Status: "ACTIVE"},
+		{ID: "666666666666", Name: "B", Status: "ACTIVE"}, // Names compare case-insensitively
+	}
+	tests := []struct {
+		name string
+		keys []SortKey
+		want []string
+	}{
+		{"name", []SortKey{{Field: "name"}}, []string{"111111111111", "222222222222", "033333333333", "444444444444", "555555555555", "666666666666"}},
+		// The ID tie-break stays ascending when the key is descending
+		{"name descending", []SortKey{{Field: "name", Descending: true}}, []string{"033333333333", "444444444444", "555555555555", "666666666666", "111111111111", "222222222222"}},
+		{"status then name", []SortKey{{Field: "status"}, {Field: "name"}}, []string{"111111111111", "222222222222", "033333333333", "555555555555", "666666666666", "444444444444"}},
+	}
+
+	// Rotations of the input, with the first half reversed, must all give the same order
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for shift := range accounts {
+				shuffled := append(slices.Clone(accounts[shift:]), accounts[:shift]...)
+				slices.Reverse(shuffled[:len(shuffled)/2])
+				SortAccounts(shuffled, tt.keys)
+				if got := ids(shuffled); !slices.Equal(got, tt.want) {
+					t.Errorf("input rotated by %d: SortAccounts() = %v, want %v", shift, got, tt.want)
+				}
+			}
+		})
+	}
+}